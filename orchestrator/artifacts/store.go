@@ -0,0 +1,383 @@
+// Package artifacts implements a minimal, TUF-inspired artifact store for
+// phase results: every expert output is written as a content-addressed blob
+// (sha256 digest), and each phase's full set of artifacts is described by a
+// signed manifest with a monotonically increasing version number. A phase
+// can only be approved once its manifest's signature and every listed
+// digest verify, so a corrupted blob or a tampered manifest on disk is
+// caught before it can flow into the next phase.
+package artifacts
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Artifact describes one expert's output within a signed Manifest.
+type Artifact struct {
+	Role      string    `json:"role"`
+	Digest    string    `json:"digest"` // hex-encoded sha256 of the blob
+	Size      int64     `json:"size"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Manifest is the signed, versioned record of every artifact belonging to
+// one phase. Manifests are append-only: rolling back or rotating the
+// signing key both produce a new, higher version rather than mutating an
+// existing one, so the version history is a complete audit trail.
+type Manifest struct {
+	TaskID    string     `json:"taskId"`
+	PhaseID   string     `json:"phaseId"`
+	Version   int        `json:"version"`
+	KeyID     string     `json:"keyId"` // hex-encoded public key that produced Signature
+	Artifacts []Artifact `json:"artifacts"`
+	SignedAt  time.Time  `json:"signedAt"`
+	Signature []byte     `json:"signature"`
+}
+
+// signedBytes returns the canonical encoding a Manifest's signature is
+// computed over: every field except Signature itself.
+func (m *Manifest) signedBytes() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Store owns the orchestrator's root signing key and persists artifact
+// blobs plus their signed manifests under baseDir. One Store is created per
+// orchestrator process; like identity.CA, its key is not persisted, so a
+// restart re-signs nothing retroactively — manifests signed by a prior
+// process's key remain verifiable only against that key's KeyID, which is
+// why trustedKeys (unlike priv) is persisted under baseDir/keys: Verify must
+// keep recognizing every key this store ever signed with, across restarts.
+type Store struct {
+	baseDir string
+
+	mu          sync.Mutex
+	priv        ed25519.PrivateKey
+	pub         ed25519.PublicKey
+	trustedKeys map[string]ed25519.PublicKey // keyId (hex) -> public key this store has ever signed with
+	history     map[string][]*Manifest       // key is taskID+"/"+phaseID, ordered oldest-to-newest
+}
+
+func manifestKey(taskID, phaseID string) string {
+	return taskID + "/" + phaseID
+}
+
+func (s *Store) manifestDir(taskID, phaseID string) string {
+	return filepath.Join(s.baseDir, "manifests", taskID, phaseID)
+}
+
+func (s *Store) keyPath(keyID string) string {
+	return filepath.Join(s.baseDir, "keys", keyID)
+}
+
+// NewStore generates a fresh Ed25519 root key, ensures baseDir's "blobs",
+// "manifests" and "keys" subdirectories exist, and loads back every manifest
+// and trusted key a prior process persisted, so restarting the orchestrator
+// doesn't forget the audit trail or start rejecting still-valid manifests.
+func NewStore(baseDir string) (*Store, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate artifact signing key: %w", err)
+	}
+
+	for _, sub := range []string{"blobs", "manifests", "keys"} {
+		if err := os.MkdirAll(filepath.Join(baseDir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create artifact store at %s: %w", baseDir, err)
+		}
+	}
+
+	s := &Store{
+		baseDir:     baseDir,
+		priv:        priv,
+		pub:         pub,
+		trustedKeys: make(map[string]ed25519.PublicKey),
+		history:     make(map[string][]*Manifest),
+	}
+
+	if err := s.loadTrustedKeys(); err != nil {
+		return nil, err
+	}
+	if err := s.loadManifests(); err != nil {
+		return nil, err
+	}
+	for _, versions := range s.history {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	}
+	if err := s.trustKey(s.pub); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// trustKey records pub as trusted (persisting it under baseDir/keys) so
+// Verify will accept manifests signed with it, now and after a restart.
+// Callers must hold s.mu.
+func (s *Store) trustKey(pub ed25519.PublicKey) error {
+	keyID := hex.EncodeToString(pub)
+	if err := os.WriteFile(s.keyPath(keyID), pub, 0o644); err != nil {
+		return fmt.Errorf("failed to persist signing key %s: %w", keyID, err)
+	}
+	s.trustedKeys[keyID] = pub
+	return nil
+}
+
+// loadTrustedKeys reads every public key a prior process ever trusted back
+// off disk. Callers must hold s.mu (only NewStore calls this, before the
+// Store is shared, so no lock is taken here).
+func (s *Store) loadTrustedKeys() error {
+	dir := filepath.Join(s.baseDir, "keys")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read trusted keys from %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read trusted key %s: %w", entry.Name(), err)
+		}
+		s.trustedKeys[entry.Name()] = ed25519.PublicKey(raw)
+	}
+	return nil
+}
+
+// loadManifests walks baseDir/manifests and reloads every persisted
+// manifest into history, in the order filepath.Walk visits the files;
+// NewStore then sorts each task/phase's versions by Version. Callers must
+// hold s.mu (only NewStore calls this).
+func (s *Store) loadManifests() error {
+	root := filepath.Join(s.baseDir, "manifests")
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk manifest store at %s: %w", path, err)
+		}
+		if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", path, err)
+		}
+		var m Manifest
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return fmt.Errorf("failed to parse manifest %s: %w", path, err)
+		}
+		key := manifestKey(m.TaskID, m.PhaseID)
+		s.history[key] = append(s.history[key], &m)
+		return nil
+	})
+}
+
+// persistManifest writes m to its version file under baseDir/manifests, so
+// it survives the process that signed it. Callers must hold s.mu.
+func (s *Store) persistManifest(m *Manifest) error {
+	dir := s.manifestDir(m.TaskID, m.PhaseID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create manifest directory for %s/%s: %w", m.TaskID, m.PhaseID, err)
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest %s/%s v%d: %w", m.TaskID, m.PhaseID, m.Version, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d.json", m.Version))
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to persist manifest %s/%s v%d: %w", m.TaskID, m.PhaseID, m.Version, err)
+	}
+	return nil
+}
+
+func (s *Store) blobPath(digest string) string {
+	return filepath.Join(s.baseDir, "blobs", digest[:2], digest)
+}
+
+// PutArtifact content-addresses content under its sha256 digest, writing it
+// to disk only the first time that digest is seen, and returns the
+// Artifact record ready to be included in a Manifest.
+func (s *Store) PutArtifact(role string, content []byte) (Artifact, error) {
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	path := s.blobPath(digest)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return Artifact{}, fmt.Errorf("failed to create blob directory for %s: %w", digest, err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return Artifact{}, fmt.Errorf("failed to write blob %s: %w", digest, err)
+		}
+	} else if err != nil {
+		return Artifact{}, fmt.Errorf("failed to stat blob %s: %w", digest, err)
+	}
+
+	return Artifact{
+		Role:      role,
+		Digest:    digest,
+		Size:      int64(len(content)),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// GetArtifact reads the blob for digest back off disk, re-hashing it to
+// catch on-disk corruption before it reaches a caller.
+func (s *Store) GetArtifact(digest string) ([]byte, error) {
+	content, err := os.ReadFile(s.blobPath(digest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", digest, err)
+	}
+
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("blob %s failed digest verification on read", digest)
+	}
+	return content, nil
+}
+
+// Sign builds and signs the next Manifest version for a task/phase from the
+// given artifacts, appending it to that phase's version history.
+func (s *Store) Sign(taskID, phaseID string, arts []Artifact) (*Manifest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := manifestKey(taskID, phaseID)
+	version := len(s.history[key]) + 1
+
+	m := &Manifest{
+		TaskID:    taskID,
+		PhaseID:   phaseID,
+		Version:   version,
+		KeyID:     hex.EncodeToString(s.pub),
+		Artifacts: arts,
+		SignedAt:  time.Now(),
+	}
+
+	unsigned, err := m.signedBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest for signing: %w", err)
+	}
+	m.Signature = ed25519.Sign(s.priv, unsigned)
+
+	if err := s.persistManifest(m); err != nil {
+		return nil, err
+	}
+	s.history[key] = append(s.history[key], m)
+	return m, nil
+}
+
+// Latest returns the newest signed manifest for a task/phase, if any.
+func (s *Store) Latest(taskID, phaseID string) (*Manifest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.history[manifestKey(taskID, phaseID)]
+	if len(versions) == 0 {
+		return nil, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// Verify re-checks a manifest's signature against the store's own trusted
+// key set (never the manifest's self-reported KeyID alone — a manifest
+// carrying an attacker-controlled key and a matching signature must not
+// verify just because it's internally consistent) and re-hashes every one
+// of its artifacts against the blobs on disk. It returns the first mismatch
+// found, wrapped with enough context to log or surface as a rejection
+// reason.
+func (s *Store) Verify(m *Manifest) error {
+	s.mu.Lock()
+	pub, trusted := s.trustedKeys[m.KeyID]
+	s.mu.Unlock()
+	if !trusted {
+		return fmt.Errorf("manifest %s/%s v%d signed by untrusted key %s", m.TaskID, m.PhaseID, m.Version, m.KeyID)
+	}
+
+	unsigned, err := m.signedBytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest %s/%s v%d for verification: %w", m.TaskID, m.PhaseID, m.Version, err)
+	}
+	if !ed25519.Verify(pub, unsigned, m.Signature) {
+		return fmt.Errorf("manifest %s/%s v%d failed signature verification", m.TaskID, m.PhaseID, m.Version)
+	}
+
+	for _, a := range m.Artifacts {
+		content, err := s.GetArtifact(a.Digest)
+		if err != nil {
+			return fmt.Errorf("manifest %s/%s v%d: artifact %q: %w", m.TaskID, m.PhaseID, m.Version, a.Role, err)
+		}
+		if int64(len(content)) != a.Size {
+			return fmt.Errorf("manifest %s/%s v%d: artifact %q size mismatch: manifest says %d, blob is %d", m.TaskID, m.PhaseID, m.Version, a.Role, a.Size, len(content))
+		}
+	}
+	return nil
+}
+
+// Rollback re-signs the artifact set from the previous manifest version as
+// a new, higher version and appends it to the history, restoring the prior
+// set of artifacts without ever deleting the record that superseded it.
+func (s *Store) Rollback(taskID, phaseID string) (*Manifest, error) {
+	s.mu.Lock()
+	key := manifestKey(taskID, phaseID)
+	versions := s.history[key]
+	if len(versions) < 2 {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("no previous manifest version to roll back to for %s/%s", taskID, phaseID)
+	}
+	previous := versions[len(versions)-2]
+	s.mu.Unlock()
+
+	return s.Sign(taskID, phaseID, previous.Artifacts)
+}
+
+// RotateRootKey generates a fresh Ed25519 key pair and resigns the latest
+// manifest of every task/phase pair under it, exactly as a TUF root-key
+// rotation re-issues trust in existing metadata: the artifact bytes and
+// their digests are untouched, only the signature and KeyID change, and the
+// rotation itself becomes a new manifest version so it shows up in the
+// version history.
+func (s *Store) RotateRootKey() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate replacement signing key: %w", err)
+	}
+	s.priv, s.pub = priv, pub
+	if err := s.trustKey(pub); err != nil {
+		return err
+	}
+
+	for key, versions := range s.history {
+		latest := versions[len(versions)-1]
+		resigned := &Manifest{
+			TaskID:    latest.TaskID,
+			PhaseID:   latest.PhaseID,
+			Version:   latest.Version + 1,
+			KeyID:     hex.EncodeToString(pub),
+			Artifacts: latest.Artifacts,
+			SignedAt:  time.Now(),
+		}
+		unsigned, err := resigned.signedBytes()
+		if err != nil {
+			return fmt.Errorf("failed to encode manifest %s for re-signing: %w", key, err)
+		}
+		resigned.Signature = ed25519.Sign(priv, unsigned)
+		if err := s.persistManifest(resigned); err != nil {
+			return err
+		}
+		s.history[key] = append(versions, resigned)
+	}
+	return nil
+}
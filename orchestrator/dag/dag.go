@@ -0,0 +1,254 @@
+// Package dag implements a small, generic dependency scheduler modeled on
+// Argo Workflows' DAG template: callers describe a set of nodes with
+// dependencies on other nodes by ID, and Scheduler runs them to completion,
+// launching every node whose dependencies have all completed concurrently
+// (bounded by a worker pool) instead of forcing a strict linear order. A
+// node whose dependency failed is never executed; it is recorded as omitted
+// so the failure still shows up in the result set instead of disappearing
+// silently.
+package dag
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Node is one unit of schedulable work. ID must be unique within a single
+// Run call; Dependencies lists the IDs that must reach "completed" before
+// this node becomes eligible to run.
+type Node struct {
+	ID           string
+	Dependencies []string
+}
+
+// Status is the terminal state Scheduler assigns to a node once a Run call
+// settles.
+type Status string
+
+const (
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	// StatusOmitted marks a node that was never executed because at least
+	// one of its dependencies failed (or was itself omitted).
+	StatusOmitted Status = "omitted"
+)
+
+// Result is what Run reports for a single node.
+type Result struct {
+	Status Status
+	Reason string // set for StatusFailed and StatusOmitted
+}
+
+// ExecFunc runs a single node's work. A non-nil error marks the node (and
+// everything that transitively depends on it) as failed/omitted.
+type ExecFunc func(ctx context.Context, id string) error
+
+// DetectCycle reports the first dependency cycle found among nodes, walking
+// each node's ancestry with the standard white/gray/black DFS coloring. It
+// also catches a node naming a dependency ID that isn't in nodes at all,
+// since that can never become ready. Callers should run this at
+// plan-ingestion time, before Scheduler.Run ever sees the graph: Run assumes
+// an acyclic, fully-resolved graph and will otherwise deadlock waiting for a
+// dependency that can never complete.
+func DetectCycle(nodes []Node) error {
+	byID := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	for _, n := range nodes {
+		for _, dep := range n.Dependencies {
+			if _, ok := byID[dep]; !ok {
+				return fmt.Errorf("node %q depends on unknown node %q", n.ID, dep)
+			}
+		}
+	}
+
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := make(map[string]int, len(nodes))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch color[id] {
+		case black:
+			return nil
+		case gray:
+			path = append(path, id)
+			return fmt.Errorf("dependency cycle detected: %v", path)
+		}
+		color[id] = gray
+		path = append(path, id)
+		for _, dep := range byID[id].Dependencies {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		color[id] = black
+		return nil
+	}
+
+	for _, n := range nodes {
+		if color[n.ID] == white {
+			if err := visit(n.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Ancestors returns the IDs of every node that targets transitively depend
+// on, plus the targets themselves. A caller uses this to prune a DAG down to
+// only the work needed to produce a partial result (the "target" list), so
+// Run only schedules nodes on the path to something the user actually
+// asked for.
+func Ancestors(nodes []Node, targets []string) map[string]bool {
+	byID := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	keep := make(map[string]bool, len(targets))
+	var mark func(id string)
+	mark = func(id string) {
+		if keep[id] {
+			return
+		}
+		keep[id] = true
+		for _, dep := range byID[id].Dependencies {
+			mark(dep)
+		}
+	}
+	for _, t := range targets {
+		mark(t)
+	}
+	return keep
+}
+
+// Scheduler runs a set of Nodes to completion, honoring dependencies and
+// bounding how many nodes execute concurrently.
+type Scheduler struct {
+	// Concurrency caps how many nodes run at once. Values <= 0 mean
+	// unbounded.
+	Concurrency int
+}
+
+// Run schedules every node in nodes whose transitive dependencies all
+// resolve to StatusCompleted, waits for them all to settle, and returns a
+// Result per node. If targets is non-empty, only targets and their
+// ancestors are scheduled; every other node is reported as omitted. Run
+// does not call DetectCycle itself — callers must validate the graph first.
+func (s *Scheduler) Run(ctx context.Context, nodes []Node, targets []string, exec ExecFunc) map[string]Result {
+	results := make(map[string]Result, len(nodes))
+	var mu sync.Mutex
+	setResult := func(id string, r Result) {
+		mu.Lock()
+		results[id] = r
+		mu.Unlock()
+	}
+
+	var scheduled map[string]bool
+	if len(targets) > 0 {
+		scheduled = Ancestors(nodes, targets)
+	}
+	for _, n := range nodes {
+		if scheduled != nil && !scheduled[n.ID] {
+			setResult(n.ID, Result{Status: StatusOmitted, Reason: "not on the path to any requested target"})
+		}
+	}
+
+	byID := make(map[string]Node, len(nodes))
+	remaining := make(map[string]int, len(nodes)) // count of not-yet-settled dependencies
+	dependents := make(map[string][]string)       // nodeID -> IDs that depend on it
+	for _, n := range nodes {
+		if scheduled != nil && !scheduled[n.ID] {
+			continue
+		}
+		byID[n.ID] = n
+		remaining[n.ID] = len(n.Dependencies)
+		for _, dep := range n.Dependencies {
+			dependents[dep] = append(dependents[dep], n.ID)
+		}
+	}
+
+	var sem chan struct{}
+	if s.Concurrency > 0 {
+		sem = make(chan struct{}, s.Concurrency)
+	}
+
+	var wg sync.WaitGroup
+	var settle func(id string)
+
+	omitDownstream := func(id, reason string) {
+		var walk func(string)
+		seen := map[string]bool{}
+		walk = func(from string) {
+			for _, dep := range dependents[from] {
+				if seen[dep] {
+					continue
+				}
+				seen[dep] = true
+				mu.Lock()
+				_, already := results[dep]
+				mu.Unlock()
+				if !already {
+					setResult(dep, Result{Status: StatusOmitted, Reason: reason})
+				}
+				walk(dep)
+			}
+		}
+		walk(id)
+	}
+
+	run := func(id string) {
+		defer wg.Done()
+		if sem != nil {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+		}
+
+		if err := exec(ctx, id); err != nil {
+			setResult(id, Result{Status: StatusFailed, Reason: err.Error()})
+			omitDownstream(id, fmt.Sprintf("dependency failed: %s", id))
+		} else {
+			setResult(id, Result{Status: StatusCompleted})
+		}
+
+		for _, dep := range dependents[id] {
+			mu.Lock()
+			remaining[dep]--
+			ready := remaining[dep] == 0
+			_, already := results[dep]
+			mu.Unlock()
+			if ready && !already {
+				wg.Add(1)
+				go settle(dep)
+			}
+		}
+	}
+	settle = run
+
+	// Collect the initial ready IDs into a plain slice before spawning any
+	// goroutine: run() mutates remaining under mu as soon as the first node
+	// finishes, and ranging over the remaining map concurrently with that
+	// write is a fatal concurrent map iteration/write, not just a race.
+	var ready []string
+	for id, deps := range remaining {
+		if deps == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for _, id := range ready {
+		wg.Add(1)
+		go settle(id)
+	}
+	wg.Wait()
+
+	return results
+}
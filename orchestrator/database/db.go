@@ -1,12 +1,17 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	_ "github.com/lib/pq"
+
+	"agentic-engineering-system/logging"
+	"agentic-engineering-system/tasktree"
+	"agentic-engineering-system/tracing"
 )
 
 type DB struct {
@@ -103,7 +108,16 @@ func (db *DB) migrate() error {
 	return err
 }
 
-func (db *DB) SaveTask(task *TaskExecution) error {
+// SaveTask and GetTask take ctx purely for structured logging/tracing
+// propagation (run_id, task_id) - this package is currently dead code,
+// superseded by storage.Interface/taskStore in main.go, but keeps the same
+// ctx-first conventions as the live persistence path in case it's ever
+// revived for a deployment that genuinely wants Postgres.
+func (db *DB) SaveTask(ctx context.Context, task *TaskExecution) error {
+	ctx, span := tracing.StartDBSpan(ctx, "save_task", task.ID)
+	defer span.End()
+	dbLog := logging.FromContext(ctx).With(logging.Fields{"task_id": task.ID})
+
 	now := time.Now()
 	task.UpdatedAt = now
 
@@ -125,13 +139,22 @@ func (db *DB) SaveTask(task *TaskExecution) error {
 		updated_at = EXCLUDED.updated_at
 	`
 
-	_, err = db.conn.Exec(query, task.ID, task.Task, task.Status, task.Result, task.Error,
+	_, err = db.conn.ExecContext(ctx, query, task.ID, task.Task, task.Status, task.Result, task.Error,
 		task.Started, phasesJSON, task.CurrentPhase, task.RequiresUserApproval, task.CreatedAt, now)
+	if err != nil {
+		dbLog.Error("Failed to save task: %v", err)
+		return err
+	}
 
-	return err
+	dbLog.Info("Saved task")
+	return nil
 }
 
-func (db *DB) GetTask(id string) (*TaskExecution, error) {
+func (db *DB) GetTask(ctx context.Context, id string) (*TaskExecution, error) {
+	ctx, span := tracing.StartDBSpan(ctx, "get_task", id)
+	defer span.End()
+	dbLog := logging.FromContext(ctx).With(logging.Fields{"task_id": id})
+
 	query := `
 	SELECT id, task, status, result, error, started, phases, current_phase, requires_user_approval, created_at, updated_at
 	FROM task_executions WHERE id = $1
@@ -140,7 +163,7 @@ func (db *DB) GetTask(id string) (*TaskExecution, error) {
 	var task TaskExecution
 	var phasesJSON []byte
 
-	err := db.conn.QueryRow(query, id).Scan(
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(
 		&task.ID, &task.Task, &task.Status, &task.Result, &task.Error,
 		&task.Started, &phasesJSON, &task.CurrentPhase, &task.RequiresUserApproval,
 		&task.CreatedAt, &task.UpdatedAt,
@@ -148,8 +171,10 @@ func (db *DB) GetTask(id string) (*TaskExecution, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
+			dbLog.Info("Task not found")
 			return nil, nil
 		}
+		dbLog.Error("Failed to get task: %v", err)
 		return nil, err
 	}
 
@@ -247,3 +272,35 @@ func (db *DB) DeleteTask(id string) error {
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
+
+// WatchAndPersistPhaseResults subscribes to tree's change stream and
+// persists every ResultSet event asynchronously via SavePhaseResult,
+// instead of the orchestrator having to call SavePhaseResult inline on the
+// critical path of finishing a phase. It runs until ctx is done.
+//
+// tasktree.Node has no notion of "phase" or "expert role" - those are
+// orchestrator-level concepts above the tree - so this treats the node's
+// own ID as both task_id and phase_id, and its ParentID as the expert role
+// slot, which is adequate for this package's dead-code/illustrative status
+// (see SaveTask's doc comment) but would need a real task/phase/expert
+// mapping threaded through Event before this could back a live deployment.
+func (db *DB) WatchAndPersistPhaseResults(ctx context.Context, tree *tasktree.Tree) error {
+	events, err := tree.Watch(ctx, tasktree.WatchFilter{})
+	if err != nil {
+		return err
+	}
+
+	watchLog := logging.FromContext(ctx).With(logging.Fields{"component": "database.watch"})
+	go func() {
+		for ev := range events {
+			if ev.Kind != tasktree.ResultSet {
+				continue
+			}
+			if err := db.SavePhaseResult(ev.NodeID, ev.NodeID, ev.ParentID, ev.Result); err != nil {
+				watchLog.Error("Failed to persist phase result for node %s: %v", ev.NodeID, err)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sync"
+	"time"
+
+	"agentic-engineering-system/docker"
+)
+
+// sseClientInfo records one active SSE subscriber for /debug/sse-clients.
+type sseClientInfo struct {
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+var (
+	sseClientsMu sync.RWMutex
+	sseClients   = make(map[string][]sseClientInfo) // taskID -> subscribers
+)
+
+// registerSSEClient records a new SSE subscriber for taskID and returns a
+// func to remove it again when the connection closes.
+func registerSSEClient(taskID, remoteAddr string) func() {
+	info := sseClientInfo{RemoteAddr: remoteAddr, ConnectedAt: time.Now()}
+
+	sseClientsMu.Lock()
+	sseClients[taskID] = append(sseClients[taskID], info)
+	sseClientsMu.Unlock()
+
+	return func() {
+		sseClientsMu.Lock()
+		defer sseClientsMu.Unlock()
+		clients := sseClients[taskID]
+		for i, c := range clients {
+			if c == info {
+				sseClients[taskID] = append(clients[:i], clients[i+1:]...)
+				break
+			}
+		}
+		if len(sseClients[taskID]) == 0 {
+			delete(sseClients, taskID)
+		}
+	}
+}
+
+// requireDebugToken gates an entire handler subtree behind a bearer token
+// read from DEBUG_TOKEN. If the env var isn't set, the subtree 404s instead
+// of 401, so an operator who never opted into the debug surface doesn't
+// even learn it exists.
+func requireDebugToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("DEBUG_TOKEN")
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newDebugMux builds the xDS-style operator debug surface: a family of
+// read-only introspection endpoints plus the standard net/http/pprof
+// profiles, all mounted on their own mux so requireDebugToken can gate the
+// whole subtree in one place instead of each pprof handler bypassing it.
+func newDebugMux() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/tasks", handleDebugTasks)
+	mux.HandleFunc("/debug/task/{id}/tree", handleDebugTaskTree)
+	mux.HandleFunc("/debug/agents", handleDebugAgents)
+	mux.HandleFunc("/debug/sse-clients", handleDebugSSEClients)
+	mux.HandleFunc("/debug/wshub", handleDebugWSHub)
+	mux.HandleFunc("/debug/configz", handleDebugConfigz)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// debugTaskSnapshot is currentTasks's in-memory state for one task: the
+// task's regular JSON fields plus whether it has a task tree (and that
+// tree's node count) and whether its execution context is set. Fields
+// TaskExecution tags `json:"-"` (e.g. the tree itself) are still not
+// serialized, since the embedded struct's tags apply here as well.
+type debugTaskSnapshot struct {
+	*TaskExecution
+	HasTree    bool `json:"hasTree"`
+	NodeCount  int  `json:"nodeCount,omitempty"`
+	ContextSet bool `json:"contextSet"`
+}
+
+func handleDebugTasks(w http.ResponseWriter, r *http.Request) {
+	tasksMutex.RLock()
+	defer tasksMutex.RUnlock()
+
+	snapshots := make(map[string]debugTaskSnapshot, len(currentTasks))
+	for id, execution := range currentTasks {
+		snap := debugTaskSnapshot{TaskExecution: execution, ContextSet: execution.Context != nil}
+		if execution.Tree != nil {
+			snap.HasTree = true
+			snap.NodeCount = len(execution.Tree.Snapshot())
+		}
+		snapshots[id] = snap
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+func handleDebugTaskTree(w http.ResponseWriter, r *http.Request) {
+	taskID := r.PathValue("id")
+
+	tasksMutex.RLock()
+	execution, exists := currentTasks[taskID]
+	tasksMutex.RUnlock()
+
+	if !exists || execution.Tree == nil {
+		http.Error(w, "task not found or has no tree", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(execution.Tree.Snapshot())
+}
+
+func handleDebugAgents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dockerManager.ListAgents())
+}
+
+func handleDebugSSEClients(w http.ResponseWriter, r *http.Request) {
+	sseClientsMu.RLock()
+	defer sseClientsMu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sseClients)
+}
+
+func handleDebugWSHub(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if wsHub == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"clients": []interface{}{}})
+		return
+	}
+	json.NewEncoder(w).Encode(wsHub.DebugClients())
+}
+
+// debugConfig is the effective runtime configuration an operator needs to
+// diagnose a mis-deploy without shelling into the container.
+type debugConfig struct {
+	Port                   string        `json:"port"`
+	DBPath                 string        `json:"dbPath"`
+	ArtifactStorePath      string        `json:"artifactStorePath"`
+	DockerBackend          string        `json:"dockerBackend"`
+	CapabilityHandshakeTTL time.Duration `json:"capabilityHandshakeTimeoutNs"`
+	AgentSVIDTTL           time.Duration `json:"agentSvidTtlNs"`
+	OrchestratorSVIDTTL    time.Duration `json:"orchestratorSvidTtlNs"`
+	DebugTokenConfigured   bool          `json:"debugTokenConfigured"`
+}
+
+func handleDebugConfigz(w http.ResponseWriter, r *http.Request) {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	cfg := debugConfig{
+		Port:                   port,
+		DBPath:                 "orchestrator.db",
+		ArtifactStorePath:      "artifacts",
+		DockerBackend:          string(docker.DefaultRuntimeConfig().Backend),
+		CapabilityHandshakeTTL: docker.CapabilityHandshakeTimeout,
+		AgentSVIDTTL:           docker.DefaultSVIDTTL,
+		OrchestratorSVIDTTL:    docker.OrchestratorSVIDTTL,
+		DebugTokenConfigured:   os.Getenv("DEBUG_TOKEN") != "",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
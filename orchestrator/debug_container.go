@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 
 	"agentic-engineering-system/docker"
+	"agentic-engineering-system/identity"
 )
 
 func debugContainer() {
 	ctx := context.Background()
-	dockerManager, err := docker.NewManager(ctx)
+	dockerManager, err := docker.NewManager(ctx, docker.DefaultRuntimeConfig())
 	if err != nil {
 		log.Fatalf("Failed to create docker manager: %v", err)
 	}
@@ -18,10 +20,12 @@ func debugContainer() {
 
 	// Spawn agent
 	log.Printf("🐳 Spawning agent container...")
-	agentContainer, err := dockerManager.SpawnAgent(ctx)
+	spiffeID := fmt.Sprintf("spiffe://%s/debug/manual-test", identity.TrustDomain)
+	agentContainer, _, caps, err := dockerManager.SpawnAgent(ctx, spiffeID)
 	if err != nil {
 		log.Fatalf("Failed to spawn agent container: %v", err)
 	}
+	log.Printf("🤝 Agent advertised capabilities: %+v", caps)
 	defer func() {
 		log.Printf("🧹 Cleaning up agent container %s", agentContainer.ID[:12])
 		if err := dockerManager.StopAgent(ctx, agentContainer.ID); err != nil {
@@ -32,11 +36,19 @@ func debugContainer() {
 	log.Printf("✅ Agent container spawned: %s at %s", agentContainer.ID[:12], agentContainer.Address)
 	log.Printf("Container ID: %s", agentContainer.ID)
 
-	// Get container logs
-	if logs, logErr := dockerManager.GetContainerLogs(ctx, agentContainer.ID); logErr == nil {
-		log.Printf("🔍 Container logs:\n%s", logs)
+	// Stream container logs so long-running output isn't truncated to a
+	// single 4096-byte read.
+	logCtx, cancelLogs := context.WithCancel(ctx)
+	defer cancelLogs()
+
+	if lines, logErr := dockerManager.StreamLogs(logCtx, agentContainer.ID); logErr == nil {
+		go func() {
+			for line := range lines {
+				log.Printf("🔍 [%s] %s: %s", line.Timestamp.Format("15:04:05"), line.Stream, line.Message)
+			}
+		}()
 	} else {
-		log.Printf("⚠️ Could not retrieve container logs: %v", logErr)
+		log.Printf("⚠️ Could not stream container logs: %v", logErr)
 	}
 
 	log.Printf("Press Enter to continue...")
@@ -0,0 +1,32 @@
+package docker
+
+// Capabilities is the structured document an agent container hands back
+// during its post-spawn handshake, describing what it's able to do so the
+// orchestrator can match work to it before dispatching a task rather than
+// discovering a mismatch mid-task.
+type Capabilities struct {
+	ModelIDs         []string `json:"modelIds"`
+	Tools            []string `json:"tools"` // e.g. "shell", "python", "browser", "fs_write"
+	MaxContextTokens int      `json:"maxContextTokens"`
+	AllowDelegation  bool     `json:"allowDelegation"`
+	Languages        []string `json:"languages"`
+}
+
+// Allows reports whether c's advertised Tools are a superset of required.
+// An empty required list is always satisfied.
+func (c Capabilities) Allows(required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+
+	have := make(map[string]struct{}, len(c.Tools))
+	for _, t := range c.Tools {
+		have[t] = struct{}{}
+	}
+	for _, r := range required {
+		if _, ok := have[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -2,198 +2,394 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
+	"agentic-engineering-system/identity"
+	"agentic-engineering-system/redact"
+	"agentic-engineering-system/runtime"
 )
 
+// CapabilityHandshakeTimeout bounds how long SpawnAgent waits for a
+// freshly-healthy agent to answer its GET /capabilities handshake.
+const CapabilityHandshakeTimeout = 10 * time.Second
+
+// DefaultSVIDTTL is how long an agent's minted identity remains valid.
+// Short enough that a leaked SVID stops being useful quickly; long enough
+// that it outlives any single expert task under normal conditions.
+const DefaultSVIDTTL = 15 * time.Minute
+
+// OrchestratorSVIDTTL is longer-lived, since the orchestrator's own identity
+// is rotated proactively by Manager.OrchestratorSVID rather than left to
+// expire mid-call.
+const OrchestratorSVIDTTL = time.Hour
+
+// Manager owns port allocation for agent sandboxes and delegates the actual
+// container lifecycle to a pluggable runtime.Runtime, so the backend
+// (Docker, containerd+runc, or a hardened gVisor/Kata handler) can be
+// swapped via RuntimeConfig without touching any caller of this type.
 type Manager struct {
-	cli         *client.Client
-	ctx         context.Context
-	nextPort    int
-	activePorts map[string]bool
-	containers  map[string]string // Map container ID to port
-	lock        sync.Mutex
+	ctx        context.Context
+	rt         runtime.Runtime
+	secrets    SecretProvider
+	redact     *redact.Writer
+	logger     *log.Logger
+	ports      *PortAllocator
+	containers map[string]agentRecord // Map container ID to its bookkeeping record
+	lock       sync.Mutex
+
+	capabilities map[string]Capabilities // Map container ID to its advertised handshake response.
+
+	identityCA   *identity.CA
+	svidTTL      time.Duration
+	orchSVID     *identity.SVID
+	orchSVIDLock sync.Mutex
+}
+
+// AgentContainer is a handle to a spawned agent sandbox.
+type AgentContainer = runtime.Container
+
+// agentRecord is Manager's internal bookkeeping for one live container,
+// beyond what runtime.Container itself carries.
+type agentRecord struct {
+	port      string
+	address   string
+	spawnedAt time.Time
+	owner     string // caller-supplied description, e.g. "task-123/phase-1/expert:backend"
+}
+
+// AgentInfo is a read-only snapshot of one live agent container, for
+// operator-facing introspection (the /debug/agents endpoint).
+type AgentInfo struct {
+	ID        string        `json:"id"`
+	Address   string        `json:"address"`
+	Port      string        `json:"port"`
+	SpawnedAt time.Time     `json:"spawnedAt"`
+	Uptime    time.Duration `json:"uptimeNs"`
+	Owner     string        `json:"owner,omitempty"`
 }
 
-type AgentContainer struct {
-	ID      string
-	Address string // e.g., "localhost:50060"
-	Port    string
+// RuntimeConfig selects the sandbox backend and its runtime-specific
+// options. It's a thin re-export of runtime.Config so callers don't need to
+// import the runtime package directly.
+type RuntimeConfig = runtime.Config
+
+// DefaultRuntimeConfig returns the plain Docker backend with no extra
+// sandboxing, matching the orchestrator's historical behavior.
+func DefaultRuntimeConfig() RuntimeConfig {
+	return runtime.DefaultConfig()
 }
 
-func NewManager(ctx context.Context) (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func NewManager(ctx context.Context, cfg RuntimeConfig) (*Manager, error) {
+	rt, err := runtime.New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := NewPortAllocator(DefaultPortAllocatorOptions())
 	if err != nil {
 		return nil, err
 	}
-	return &Manager{
-		cli:         cli,
-		ctx:         ctx,
-		nextPort:    50060, // Start from a high port number
-		activePorts: make(map[string]bool),
-		containers:  make(map[string]string),
-	}, nil
+
+	ca, err := identity.NewCA()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SPIFFE identity CA: %w", err)
+	}
+
+	redactedOut := redact.NewWriter(os.Stderr)
+	m := &Manager{
+		ctx:          ctx,
+		rt:           rt,
+		secrets:      envSecretProvider{},
+		redact:       redactedOut,
+		logger:       log.New(redactedOut, "", log.LstdFlags),
+		ports:        ports,
+		containers:   make(map[string]agentRecord),
+		capabilities: make(map[string]Capabilities),
+		identityCA:   ca,
+		svidTTL:      DefaultSVIDTTL,
+	}
+
+	// Rediscover agent containers that survived a prior orchestrator
+	// process (identified by the label every Spawn stamps on), so we don't
+	// leak them and don't hand out ports they're already holding.
+	survivors, err := rt.Reconcile(ctx)
+	if err != nil {
+		m.logger.Printf("⚠️ Could not reconcile surviving agent containers: %v", err)
+	}
+	for _, c := range survivors {
+		if port, convErr := strconv.Atoi(c.Port); convErr == nil {
+			ports.Reconcile(port, c.ID)
+		}
+		m.containers[c.ID] = agentRecord{port: c.Port, address: c.Address, spawnedAt: time.Now(), owner: "reconciled"}
+		m.logger.Printf("🔁 Rediscovered surviving agent container %s on port %s", c.ID[:12], c.Port)
+	}
+
+	return m, nil
+}
+
+// SetSecretProvider overrides how Manager sources secret material for
+// spawned agents. The default envSecretProvider reads from the
+// orchestrator's own environment, which is only a step above the old
+// Env-var-on-the-container approach; swap in a TmpfsSecretProvider,
+// SwarmSecretProvider, or ExternalSecretProvider for production use.
+func (m *Manager) SetSecretProvider(p SecretProvider) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.secrets = p
 }
 
-func (m *Manager) SpawnAgent(ctx context.Context) (*AgentContainer, error) {
+// SetSVIDTTL overrides how long agent SVIDs minted by SpawnAgent remain
+// valid, letting deployments trade off rotation overhead against exposure
+// window. The default is DefaultSVIDTTL.
+func (m *Manager) SetSVIDTTL(ttl time.Duration) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	m.svidTTL = ttl
+}
 
-	port := strconv.Itoa(m.nextPort)
-	m.nextPort++
+// TrustBundlePEM returns the root certificate agent and orchestrator SVIDs
+// are verified against, for callers that need to hand it out for
+// observability (e.g. the /api/identity/bundle endpoint).
+func (m *Manager) TrustBundlePEM() []byte {
+	return m.identityCA.TrustBundlePEM()
+}
 
-	// Use the exact same approach as manual Docker run that works
-	// docker run --rm -d -p PORT:PORT -e OPENAI_API_KEY=$OPENAI_API_KEY agentic-engineering-system_generic_agent python agent.py PORT
+// OrchestratorSVID returns the orchestrator's own long-lived identity,
+// minting it on first use and rotating it once it's within a minute of
+// expiry so a long-running process never dials out on a stale SVID.
+func (m *Manager) OrchestratorSVID() (*identity.SVID, error) {
+	m.orchSVIDLock.Lock()
+	defer m.orchSVIDLock.Unlock()
 
-	hostBinding := nat.PortBinding{
-		HostIP:   "", // Use default (empty) instead of "0.0.0.0"
-		HostPort: port,
+	if m.orchSVID == nil || m.orchSVID.Expired(time.Now().Add(time.Minute)) {
+		svid, err := m.identityCA.IssueSVID(identity.OrchestratorSPIFFEID, OrchestratorSVIDTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mint orchestrator SVID: %w", err)
+		}
+		m.orchSVID = svid
+		m.logger.Printf("🪪 Minted orchestrator SVID %s (expires %s)", svid.SPIFFEID, svid.NotAfter.Format(time.RFC3339))
 	}
-	containerPort, err := nat.NewPort("tcp", port)
+
+	return m.orchSVID, nil
+}
+
+// SpawnAgent starts a new agent sandbox, mints it a SPIFFE SVID scoped to
+// spiffeID, injecting the SVID cert/key and the trust bundle alongside the
+// existing OPENAI_API_KEY secret via the same tmpfs mount mechanism, and
+// performs a capability handshake once the container reports healthy. The
+// returned SVID lets the caller validate the agent's identity (and enforce
+// its TTL) before trusting any result it returns; the returned Capabilities
+// lets the caller refuse to dispatch work the agent never advertised
+// support for.
+func (m *Manager) SpawnAgent(ctx context.Context, spiffeID string) (*AgentContainer, *identity.SVID, Capabilities, error) {
+	portNum, err := m.ports.Allocate()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create port: %v", err)
-	}
-
-	portBindings := nat.PortMap{containerPort: []nat.PortBinding{hostBinding}}
-
-	// Get the current OPENAI_API_KEY - ensure we get the fresh value
-	openaiKey := os.Getenv("OPENAI_API_KEY")
-	if openaiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
-	}
-	log.Printf("🔑 Using OpenAI API key ending in: ...%s", openaiKey[len(openaiKey)-4:])
-	log.Printf("🔑 Full API key length: %d characters", len(openaiKey))
-	log.Printf("🔑 API key starts with: %s...", openaiKey[:20])
-	
-	// Prepare environment variables for the container
-	envVars := []string{"OPENAI_API_KEY=" + openaiKey}
-	log.Printf("🔑 Environment variable being passed: OPENAI_API_KEY=%s...%s (length: %d)", 
-		openaiKey[:20], openaiKey[len(openaiKey)-4:], len(openaiKey))
-	
-	// Create with minimal configuration that matches manual approach
-	resp, err := m.cli.ContainerCreate(ctx, &container.Config{
-		Image:        "agentic-engineering-system_generic_agent",
-		Cmd:          []string{"python", "agent.py", port},
-		Env:          envVars,
-		ExposedPorts: nat.PortSet{containerPort: struct{}{}}, // Explicitly expose the port
-	}, &container.HostConfig{
-		PortBindings: portBindings,
-		AutoRemove:   false, // Disable for debugging - keep containers around to inspect
-	}, nil, nil, "")
+		return nil, nil, Capabilities{}, fmt.Errorf("failed to allocate a host port: %w", err)
+	}
+	port := strconv.Itoa(portNum)
+
+	openaiKey, err := m.secrets.Provide("OPENAI_API_KEY")
 	if err != nil {
-		return nil, err
+		m.ports.ReleasePort(portNum)
+		return nil, nil, Capabilities{}, fmt.Errorf("failed to source OPENAI_API_KEY: %w", err)
 	}
+	m.redact.Register(openaiKey)
 
-	if err := m.cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-		return nil, err
+	svid, err := m.identityCA.IssueSVID(spiffeID, m.svidTTL)
+	if err != nil {
+		m.ports.ReleasePort(portNum)
+		return nil, nil, Capabilities{}, fmt.Errorf("failed to mint SVID for %s: %w", spiffeID, err)
 	}
+	m.logger.Printf("🔑 Secrets acquired from provider; injecting via tmpfs mount (redacted), identity %s", spiffeID)
 
-	log.Printf("Spawned agent container %s on port %s", resp.ID[:12], port)
-	m.containers[resp.ID] = port
-	m.activePorts[port] = true
+	container, err := m.rt.Spawn(ctx, runtime.SpawnOptions{
+		Image: "agentic-engineering-system_generic_agent",
+		Cmd:   []string{"python", "agent.py", port},
+		Port:  port,
+		Limits: runtime.ResourceLimits{
+			CPUShares: 512,
+			MemoryMB:  1024,
+			PidsLimit: 256,
+		},
+		Secrets: []runtime.SecretMount{
+			{Name: "openai_api_key", Value: openaiKey},
+			{Name: "svid_cert.pem", Value: string(svid.CertPEM)},
+			{Name: "svid_key.pem", Value: string(svid.KeyPEM)},
+			{Name: "trust_bundle.pem", Value: string(m.identityCA.TrustBundlePEM())},
+		},
+	})
+	if err != nil {
+		m.ports.ReleasePort(portNum)
+		return nil, nil, Capabilities{}, err
+	}
+	m.ports.Bind(portNum, container.ID)
 
-	// Give the container more time to start its gRPC server and initialize
-	log.Printf("Waiting for agent in container %s to initialize...", resp.ID[:12])
+	m.lock.Lock()
+	m.containers[container.ID] = agentRecord{port: port, address: container.Address, spawnedAt: time.Now()}
+	m.lock.Unlock()
 
-	// Instead of fixed wait, do health checks
-	maxWaitTime := 30 * time.Second
-	checkInterval := 1 * time.Second
-	startTime := time.Now()
+	m.logger.Printf("Spawned agent container %s on port %s", container.ID[:12], port)
 
-	for time.Since(startTime) < maxWaitTime {
-		// Try to connect to the port to see if it's accepting connections
-		conn, err := net.DialTimeout("tcp", "host.docker.internal:"+port, 2*time.Second)
-		if err == nil {
-			conn.Close()
-			log.Printf("✅ Agent container %s is ready and accepting connections", resp.ID[:12])
-			// Give the gRPC server extra time to fully initialize HTTP/2 handling
-			time.Sleep(5 * time.Second)
-			break
-		}
+	m.logger.Printf("Waiting for agent in container %s to become healthy...", container.ID[:12])
+	waitCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := m.rt.WaitHealthy(waitCtx, container.ID); err != nil {
+		m.ports.Release(container.ID)
+		return nil, nil, Capabilities{}, fmt.Errorf("container %s did not become healthy: %w", container.ID[:12], err)
+	}
+	m.logger.Printf("✅ Agent container %s is healthy and accepting connections", container.ID[:12])
 
-		// Check if we've reached the maximum wait time
-		if time.Since(startTime) >= maxWaitTime {
-			log.Printf("⚠️ Agent container %s did not become ready within %v", resp.ID[:12], maxWaitTime)
-			break
-		}
+	caps, err := m.fetchCapabilities(ctx, container.Address)
+	if err != nil {
+		m.ports.Release(container.ID)
+		return nil, nil, Capabilities{}, fmt.Errorf("capability handshake with %s failed: %w", container.ID[:12], err)
+	}
+	m.lock.Lock()
+	m.capabilities[container.ID] = caps
+	m.lock.Unlock()
+	m.logger.Printf("🤝 Agent container %s advertised capabilities: tools=%v models=%v", container.ID[:12], caps.Tools, caps.ModelIDs)
+
+	return container, svid, caps, nil
+}
+
+// fetchCapabilities performs the post-spawn handshake GET /capabilities
+// against a freshly healthy agent and decodes its advertised Capabilities.
+func (m *Manager) fetchCapabilities(ctx context.Context, address string) (Capabilities, error) {
+	handshakeCtx, cancel := context.WithTimeout(ctx, CapabilityHandshakeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(handshakeCtx, http.MethodGet, "http://"+address+"/capabilities", nil)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to build capability handshake request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("capability handshake request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-		time.Sleep(checkInterval)
+	if resp.StatusCode != http.StatusOK {
+		return Capabilities{}, fmt.Errorf("capability handshake returned status %d", resp.StatusCode)
 	}
 
-	return &AgentContainer{
-		ID:      resp.ID,
-		Address: "host.docker.internal:" + port, // Use Docker host reference to reach host-bound ports
-		Port:    port,
-	}, nil
+	var caps Capabilities
+	if err := json.NewDecoder(resp.Body).Decode(&caps); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to decode capability handshake response: %w", err)
+	}
+	return caps, nil
 }
 
-func (m *Manager) StopAgent(ctx context.Context, containerID string) error {
+// SetOwner records a human-readable description of what a spawned
+// container is currently doing (e.g. "task-123/phase-1/expert:backend"), so
+// /debug/agents can show which task/expert owns each live agent instead of
+// just its bare container ID.
+func (m *Manager) SetOwner(containerID, owner string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rec, ok := m.containers[containerID]
+	if !ok {
+		return
+	}
+	rec.owner = owner
+	m.containers[containerID] = rec
+}
+
+// ListAgents returns a snapshot of every live agent container, for the
+// /debug/agents endpoint.
+func (m *Manager) ListAgents() []AgentInfo {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	if port, exists := m.containers[containerID]; exists {
-		delete(m.activePorts, port)
-		delete(m.containers, containerID)
+	out := make([]AgentInfo, 0, len(m.containers))
+	for id, rec := range m.containers {
+		out = append(out, AgentInfo{
+			ID:        id,
+			Address:   rec.address,
+			Port:      rec.port,
+			SpawnedAt: rec.spawnedAt,
+			Uptime:    time.Since(rec.spawnedAt),
+			Owner:     rec.owner,
+		})
 	}
+	return out
+}
 
-	timeout := 10
-	err := m.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
-	if err != nil {
-		log.Printf("Failed to stop container %s: %v", containerID[:12], err)
-		return err
+// Capabilities returns the capability set a spawned agent advertised during
+// its handshake, and whether one is known for containerID.
+func (m *Manager) Capabilities(containerID string) (Capabilities, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	caps, ok := m.capabilities[containerID]
+	return caps, ok
+}
+
+// AllCapabilities returns a snapshot of every currently spawned agent's
+// advertised capabilities, keyed by container ID, for the
+// /api/agents/capabilities endpoint.
+func (m *Manager) AllCapabilities() map[string]Capabilities {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	out := make(map[string]Capabilities, len(m.capabilities))
+	for id, caps := range m.capabilities {
+		out[id] = caps
 	}
+	return out
+}
 
-	err = m.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{})
-	if err != nil {
-		log.Printf("Failed to remove container %s: %v", containerID[:12], err)
+// WaitHealthy blocks until the container backing id reports healthy.
+func (m *Manager) WaitHealthy(ctx context.Context, id string) error {
+	return m.rt.WaitHealthy(ctx, id)
+}
+
+func (m *Manager) StopAgent(ctx context.Context, containerID string) error {
+	m.lock.Lock()
+	delete(m.containers, containerID)
+	delete(m.capabilities, containerID)
+	m.lock.Unlock()
+	m.ports.Release(containerID)
+
+	if err := m.rt.Stop(ctx, containerID); err != nil {
+		m.logger.Printf("Failed to stop container %s: %v", containerID[:12], err)
 		return err
 	}
 
-	log.Printf("Stopped and removed agent container %s", containerID[:12])
+	m.logger.Printf("Stopped and removed agent container %s", containerID[:12])
 	return nil
 }
 
-func (m *Manager) GetContainerLogs(ctx context.Context, containerID string) (string, error) {
-	out, err := m.cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Tail:       "50", // Get last 50 lines
-	})
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
+// LogLine is a single demultiplexed, timestamped line of container output.
+type LogLine = runtime.LogLine
 
-	buf := make([]byte, 4096)
-	n, err := out.Read(buf)
-	if err != nil && err.Error() != "EOF" {
-		return "", err
-	}
+func (m *Manager) GetContainerLogs(ctx context.Context, containerID string) (string, error) {
+	return m.rt.Logs(ctx, containerID)
+}
 
-	return string(buf[:n]), nil
+// StreamLogs follows a container's combined stdout/stderr as a channel of
+// LogLines until ctx is cancelled or the container exits, so long logs
+// aren't truncated the way a single-read GetContainerLogs would.
+func (m *Manager) StreamLogs(ctx context.Context, containerID string) (<-chan LogLine, error) {
+	return m.rt.StreamLogs(ctx, containerID)
 }
 
 func (m *Manager) CleanupAllAgents() {
 	m.lock.Lock()
-	defer m.lock.Unlock()
-
 	for containerID := range m.containers {
-		timeout := 5
-		_ = m.cli.ContainerStop(m.ctx, containerID, container.StopOptions{Timeout: &timeout})
-		_ = m.cli.ContainerRemove(m.ctx, containerID, types.ContainerRemoveOptions{})
-		log.Printf("Cleaned up container %s", containerID[:12])
+		m.ports.Release(containerID)
 	}
+	m.containers = make(map[string]agentRecord)
+	m.capabilities = make(map[string]Capabilities)
+	m.lock.Unlock()
 
-	m.containers = make(map[string]string)
-	m.activePorts = make(map[string]bool)
+	if err := m.rt.Cleanup(m.ctx); err != nil {
+		m.logger.Printf("Failed to clean up agent containers: %v", err)
+	}
 }
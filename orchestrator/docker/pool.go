@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"agentic-engineering-system/identity"
+	"agentic-engineering-system/tasks"
+)
+
+// PoolOptions configures a Pool's pre-warmed agent containers.
+type PoolOptions struct {
+	MaxIdleTTL   time.Duration // How long an idle agent may sit before being reaped.
+	ReapInterval time.Duration
+}
+
+// DefaultPoolOptions returns reasonable defaults for a warm-agent pool.
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		MaxIdleTTL:   5 * time.Minute,
+		ReapInterval: 30 * time.Second,
+	}
+}
+
+// ReleaseFunc returns an acquired agent to its pool.
+type ReleaseFunc func()
+
+type pooledAgent struct {
+	container *AgentContainer
+	idleSince time.Time
+}
+
+// PoolStats are Prometheus-style counters for pool activity.
+type PoolStats struct {
+	Spawns     int64
+	Reuses     int64
+	Evictions  int64
+	WaitTimeMS int64 // Cumulative Acquire wait time across all callers.
+}
+
+// Pool pre-warms a fixed number of agent containers and hands them out to
+// callers, resetting each agent between uses instead of paying the full
+// spawn-and-wait-healthy cost on every task.
+type Pool struct {
+	manager *Manager
+	opts    PoolOptions
+
+	free  chan *pooledAgent
+	lock  sync.Mutex
+	inUse map[string]*pooledAgent
+
+	stats PoolStats
+
+	stopReaper chan struct{}
+	nextSlot   int64 // Monotonic counter handing out PoolSPIFFEID slots.
+}
+
+// NewPool pre-warms size agent containers via manager and starts a reaper
+// goroutine that recycles agents idle longer than opts.MaxIdleTTL.
+func NewPool(ctx context.Context, manager *Manager, size int, opts PoolOptions) (*Pool, error) {
+	p := &Pool{
+		manager:    manager,
+		opts:       opts,
+		free:       make(chan *pooledAgent, size),
+		inUse:      make(map[string]*pooledAgent),
+		stopReaper: make(chan struct{}),
+	}
+
+	for i := 0; i < size; i++ {
+		slot := int(atomic.AddInt64(&p.nextSlot, 1))
+		container, _, _, err := manager.SpawnAgent(ctx, identity.PoolSPIFFEID(slot))
+		if err != nil {
+			return nil, fmt.Errorf("failed to pre-warm agent %d/%d: %w", i+1, size, err)
+		}
+		atomic.AddInt64(&p.stats.Spawns, 1)
+		p.free <- &pooledAgent{container: container, idleSince: time.Now()}
+	}
+
+	go p.reap(ctx)
+
+	return p, nil
+}
+
+// Acquire blocks until a warm agent is available or ctx is cancelled.
+func (p *Pool) Acquire(ctx context.Context) (*AgentContainer, ReleaseFunc, error) {
+	start := time.Now()
+
+	select {
+	case agent, ok := <-p.free:
+		if !ok {
+			return nil, nil, fmt.Errorf("pool is closed")
+		}
+		atomic.AddInt64(&p.stats.WaitTimeMS, time.Since(start).Milliseconds())
+
+		p.lock.Lock()
+		p.inUse[agent.container.ID] = agent
+		p.lock.Unlock()
+
+		return agent.container, func() { p.Release(agent.container.ID) }, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// Release resets the agent and returns it to the free list. If the reset
+// fails, the agent is torn down and replaced rather than reused dirty.
+func (p *Pool) Release(containerID string) {
+	p.lock.Lock()
+	agent, ok := p.inUse[containerID]
+	delete(p.inUse, containerID)
+	p.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := tasks.ResetAgent(agent.container.Address); err != nil {
+		log.Printf("⚠️ Failed to reset pooled agent %s, recreating: %v", containerID[:12], err)
+		p.recreate(agent)
+		return
+	}
+
+	atomic.AddInt64(&p.stats.Reuses, 1)
+	agent.idleSince = time.Now()
+	p.free <- agent
+}
+
+// recreate tears down agent's container and replaces it with a freshly
+// spawned one in the free list.
+func (p *Pool) recreate(agent *pooledAgent) {
+	ctx := context.Background()
+	_ = p.manager.StopAgent(ctx, agent.container.ID)
+
+	slot := int(atomic.AddInt64(&p.nextSlot, 1))
+	newContainer, _, _, err := p.manager.SpawnAgent(ctx, identity.PoolSPIFFEID(slot))
+	if err != nil {
+		log.Printf("⚠️ Failed to recreate pool agent: %v", err)
+		return
+	}
+	atomic.AddInt64(&p.stats.Spawns, 1)
+	p.free <- &pooledAgent{container: newContainer, idleSince: time.Now()}
+}
+
+// reap recreates agents that have sat idle longer than MaxIdleTTL, so a
+// pool doesn't accumulate stale long-lived containers.
+func (p *Pool) reap(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopReaper:
+			return
+		case <-ticker.C:
+			drained := len(p.free)
+			for i := 0; i < drained; i++ {
+				agent := <-p.free
+				if time.Since(agent.idleSince) <= p.opts.MaxIdleTTL {
+					p.free <- agent
+					continue
+				}
+				atomic.AddInt64(&p.stats.Evictions, 1)
+				log.Printf("🔁 Evicting idle agent %s after %s", agent.container.ID[:12], p.opts.MaxIdleTTL)
+				p.recreate(agent)
+			}
+		}
+	}
+}
+
+// Stats returns a snapshot of pool counters.
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Spawns:     atomic.LoadInt64(&p.stats.Spawns),
+		Reuses:     atomic.LoadInt64(&p.stats.Reuses),
+		Evictions:  atomic.LoadInt64(&p.stats.Evictions),
+		WaitTimeMS: atomic.LoadInt64(&p.stats.WaitTimeMS),
+	}
+}
+
+// Close stops the reaper and tears down every pooled agent, in use or not.
+func (p *Pool) Close(ctx context.Context) {
+	close(p.stopReaper)
+	close(p.free)
+
+	for agent := range p.free {
+		_ = p.manager.StopAgent(ctx, agent.container.ID)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, agent := range p.inUse {
+		_ = p.manager.StopAgent(ctx, agent.container.ID)
+	}
+}
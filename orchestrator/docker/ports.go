@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// PortAllocatorOptions configures the host port range a PortAllocator may
+// hand out and where it persists its allocation map.
+type PortAllocatorOptions struct {
+	Min, Max    int
+	PersistPath string
+}
+
+// DefaultPortAllocatorOptions matches the orchestrator's historical range.
+func DefaultPortAllocatorOptions() PortAllocatorOptions {
+	return PortAllocatorOptions{
+		Min:         50060,
+		Max:         50999,
+		PersistPath: "agent_ports.json",
+	}
+}
+
+// PortAllocator hands out host ports for agent containers from a fixed
+// range, probing each candidate with net.Listen before assigning it so it
+// never collides with something already bound on the host, and persisting
+// its allocation map to disk so a restarted Manager can pick up where it
+// left off instead of starting back at Min.
+type PortAllocator struct {
+	min, max    int
+	persistPath string
+
+	lock  sync.Mutex
+	inUse map[int]string // port -> container ID ("" while reserved but not yet bound)
+}
+
+// NewPortAllocator loads any prior allocation map from opts.PersistPath, if
+// present, and returns an allocator ready to hand out the rest of the range.
+func NewPortAllocator(opts PortAllocatorOptions) (*PortAllocator, error) {
+	a := &PortAllocator{
+		min:         opts.Min,
+		max:         opts.Max,
+		persistPath: opts.PersistPath,
+		inUse:       make(map[int]string),
+	}
+
+	data, err := os.ReadFile(opts.PersistPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &a.inUse); err != nil {
+			return nil, fmt.Errorf("failed to parse port allocation file %s: %w", opts.PersistPath, err)
+		}
+	case os.IsNotExist(err):
+		// First run; nothing to load.
+	default:
+		return nil, fmt.Errorf("failed to read port allocation file %s: %w", opts.PersistPath, err)
+	}
+
+	return a, nil
+}
+
+// Reconcile marks port as already held by containerID, e.g. because the
+// container survived an orchestrator restart and was rediscovered via its
+// label. It does not probe or persist, since the port is already in use.
+func (a *PortAllocator) Reconcile(port int, containerID string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.inUse[port] = containerID
+}
+
+// Allocate reserves the lowest free, host-bindable port in range. The
+// caller should follow up with Bind once the container holding it exists,
+// or ReleasePort if spawning it failed.
+func (a *PortAllocator) Allocate() (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for port := a.min; port <= a.max; port++ {
+		if _, taken := a.inUse[port]; taken {
+			continue
+		}
+		if !probePort(port) {
+			continue
+		}
+
+		a.inUse[port] = ""
+		if err := a.persistLocked(); err != nil {
+			delete(a.inUse, port)
+			return 0, err
+		}
+		return port, nil
+	}
+
+	return 0, fmt.Errorf("no free port available in range %d-%d", a.min, a.max)
+}
+
+// Bind records that port is now held by containerID.
+func (a *PortAllocator) Bind(port int, containerID string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.inUse[port] = containerID
+	_ = a.persistLocked()
+}
+
+// ReleasePort frees port directly, e.g. when a reserved port's container
+// failed to spawn.
+func (a *PortAllocator) ReleasePort(port int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.inUse, port)
+	_ = a.persistLocked()
+}
+
+// Release returns containerID's port to the free set.
+func (a *PortAllocator) Release(containerID string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for port, id := range a.inUse {
+		if id == containerID {
+			delete(a.inUse, port)
+		}
+	}
+	_ = a.persistLocked()
+}
+
+func (a *PortAllocator) persistLocked() error {
+	data, err := json.Marshal(a.inUse)
+	if err != nil {
+		return fmt.Errorf("failed to serialize port allocation: %w", err)
+	}
+	if err := os.WriteFile(a.persistPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to persist port allocation to %s: %w", a.persistPath, err)
+	}
+	return nil
+}
+
+// probePort reports whether port is currently free to bind on the host.
+func probePort(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
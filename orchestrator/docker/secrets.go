@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+)
+
+// SecretProvider supplies sensitive values (API keys, tokens) to an agent
+// container. Implementations hand the value to Manager, which injects it
+// via a tmpfs-backed mount instead of container.Config.Env, so it never
+// shows up in `docker inspect`, container metadata, or env-capturing log
+// aggregators.
+type SecretProvider interface {
+	// Provide returns the secret material for name.
+	Provide(name string) (string, error)
+}
+
+// envSecretProvider is the default provider, reading from the orchestrator
+// process's own environment. It exists only so Manager has a provider to
+// fall back to before the caller wires up something more durable.
+type envSecretProvider struct{}
+
+func (envSecretProvider) Provide(name string) (string, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// TmpfsSecretProvider serves secrets from a fixed in-memory map. Manager
+// mounts each one into the container as a tmpfs file at
+// /run/secrets/<name>, never touching disk on the host or in the image.
+type TmpfsSecretProvider struct {
+	values map[string]string
+}
+
+// NewTmpfsSecretProvider builds a provider over a fixed set of values, e.g.
+// loaded once at orchestrator startup from the process environment.
+func NewTmpfsSecretProvider(values map[string]string) *TmpfsSecretProvider {
+	return &TmpfsSecretProvider{values: values}
+}
+
+func (p *TmpfsSecretProvider) Provide(name string) (string, error) {
+	v, ok := p.values[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q is not configured", name)
+	}
+	return v, nil
+}
+
+// SwarmSecretProvider defers to Docker Swarm secrets when the daemon is in
+// swarm mode. Swarm already mounts secrets at /run/secrets/<name> for the
+// service, so Provide has nothing to hand back; Manager recognizes the
+// empty value and skips the tmpfs injection for that secret.
+type SwarmSecretProvider struct{}
+
+func NewSwarmSecretProvider() *SwarmSecretProvider { return &SwarmSecretProvider{} }
+
+func (p *SwarmSecretProvider) Provide(name string) (string, error) {
+	return "", nil
+}
+
+// ExternalSecretProvider fetches a secret from an external store (Vault,
+// AWS Secrets Manager, ...) through a user-supplied hook each time it's
+// needed, so rotated credentials are picked up without restarting the
+// orchestrator.
+type ExternalSecretProvider struct {
+	Fetch func(name string) (string, error)
+}
+
+func NewExternalSecretProvider(fetch func(name string) (string, error)) *ExternalSecretProvider {
+	return &ExternalSecretProvider{Fetch: fetch}
+}
+
+func (p *ExternalSecretProvider) Provide(name string) (string, error) {
+	return p.Fetch(name)
+}
@@ -0,0 +1,206 @@
+// Package hooks implements a pluggable "run tasks" gate for project phases,
+// modeled on Terraform Cloud's pre-apply run tasks: a phase can declare zero
+// or more external HTTP callback URLs that the orchestrator POSTs its plan
+// and prior artifacts to between phase completion and the approval
+// broadcast. Each hook answers pass/fail/advisory, either synchronously in
+// its HTTP response or later via a webhook callback to Runner.Resolve. A
+// failing mandatory hook auto-rejects the phase; an advisory hook only
+// annotates it. This lets a user wire in policy engines (OPA), cost
+// estimators, or security scanners as first-class phase gates without
+// touching orchestrator code.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Verdict is what a single hook reports about a phase.
+type Verdict string
+
+const (
+	VerdictPass     Verdict = "pass"
+	VerdictFail     Verdict = "fail"
+	VerdictAdvisory Verdict = "advisory"
+)
+
+// DefaultTimeout bounds how long Run waits for a single hook's callback
+// after a 202 Accepted when the hook's own TimeoutSeconds is unset.
+const DefaultTimeout = 60 * time.Second
+
+// HookSpec is one external callback a phase gates on before it can be
+// approved.
+type HookSpec struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Mandatory bool   `json:"mandatory"`
+	// TimeoutSeconds bounds how long Run waits for this hook's callback
+	// after it answers 202 Accepted. Zero means DefaultTimeout. The initial
+	// HTTP response is bounded by Runner.Client's own timeout instead.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// Result is what Run reports for a single HookSpec once it settles.
+type Result struct {
+	Hook    HookSpec `json:"hook"`
+	Verdict Verdict  `json:"verdict"`
+	Message string   `json:"message,omitempty"`
+	// Error is set when the hook couldn't be reached or timed out waiting
+	// for a callback; such a hook is treated as a failed verdict.
+	Error string `json:"error,omitempty"`
+}
+
+// Payload is the JSON body POSTed to every HookSpec's URL.
+type Payload struct {
+	TaskID      string      `json:"taskId"`
+	PhaseID     string      `json:"phaseId"`
+	Phase       interface{} `json:"phase"`
+	CallbackURL string      `json:"callbackUrl"`
+}
+
+// callbackBody is what a hook may later POST back to CallbackURL.
+type callbackBody struct {
+	Verdict Verdict `json:"verdict"`
+	Message string  `json:"message"`
+}
+
+// Runner fires every HookSpec on a phase and collects its verdict, either
+// from the hook's own HTTP response (a synchronous policy check) or, if the
+// hook responds 202 Accepted, from a later call to Resolve with the token
+// embedded in the callback URL it was given (an async scan or human review
+// queued on the hook's side).
+type Runner struct {
+	Client *http.Client
+	// CallbackBaseURL is the orchestrator's own externally-reachable base
+	// URL for hook callbacks, e.g. "http://localhost:8080/hooks/callback";
+	// each Run call appends "/<token>" to it.
+	CallbackBaseURL string
+
+	mu      sync.Mutex
+	pending map[string]chan Result // token -> waiter, set for the lifetime of one runOne call
+}
+
+// NewRunner builds a Runner that posts callback URLs rooted at
+// callbackBaseURL.
+func NewRunner(callbackBaseURL string) *Runner {
+	return &Runner{
+		Client:          &http.Client{Timeout: 30 * time.Second},
+		CallbackBaseURL: callbackBaseURL,
+		pending:         make(map[string]chan Result),
+	}
+}
+
+// Run fires every spec concurrently against plan and blocks until all of
+// them have produced a Result, in spec order.
+func (r *Runner) Run(ctx context.Context, taskID, phaseID string, specs []HookSpec, plan interface{}) []Result {
+	results := make([]Result, len(specs))
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec HookSpec) {
+			defer wg.Done()
+			results[i] = r.runOne(ctx, taskID, phaseID, spec, plan)
+		}(i, spec)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) runOne(ctx context.Context, taskID, phaseID string, spec HookSpec, plan interface{}) Result {
+	timeout := time.Duration(spec.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	token := fmt.Sprintf("%s/%s/%s-%d", taskID, phaseID, spec.Name, time.Now().UnixNano())
+	waiter := make(chan Result, 1)
+	r.mu.Lock()
+	r.pending[token] = waiter
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, token)
+		r.mu.Unlock()
+	}()
+
+	body, err := json.Marshal(Payload{
+		TaskID:      taskID,
+		PhaseID:     phaseID,
+		Phase:       plan,
+		CallbackURL: r.CallbackBaseURL + "/" + token,
+	})
+	if err != nil {
+		return Result{Hook: spec, Verdict: VerdictFail, Error: fmt.Sprintf("failed to encode hook payload: %v", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{Hook: spec, Verdict: VerdictFail, Error: fmt.Sprintf("failed to build hook request: %v", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return Result{Hook: spec, Verdict: VerdictFail, Error: fmt.Sprintf("hook %q unreachable: %v", spec.Name, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		// Async hook: it queued the check and will POST its verdict back to
+		// CallbackURL once it's done.
+		select {
+		case res := <-waiter:
+			res.Hook = spec
+			return res
+		case <-time.After(timeout):
+			return Result{Hook: spec, Verdict: VerdictFail, Error: fmt.Sprintf("timed out waiting %s for hook %q's callback", timeout, spec.Name)}
+		case <-ctx.Done():
+			return Result{Hook: spec, Verdict: VerdictFail, Error: ctx.Err().Error()}
+		}
+	}
+
+	var cb callbackBody
+	if err := json.NewDecoder(resp.Body).Decode(&cb); err != nil {
+		return Result{Hook: spec, Verdict: VerdictFail, Error: fmt.Sprintf("malformed response from hook %q: %v", spec.Name, err)}
+	}
+	return Result{Hook: spec, Verdict: cb.Verdict, Message: cb.Message}
+}
+
+// Resolve delivers a callback verdict to the in-flight Run call waiting on
+// token. It returns false if token doesn't match any pending hook (already
+// timed out, already resolved, or never existed).
+func (r *Runner) Resolve(token string, verdict Verdict, message string) bool {
+	r.mu.Lock()
+	waiter, ok := r.pending[token]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case waiter <- Result{Verdict: verdict, Message: message}:
+	default:
+	}
+	return true
+}
+
+// Aggregate reports whether results should auto-reject the phase: true iff
+// any mandatory hook's Result is VerdictFail (or an unreachable/timed-out
+// mandatory hook, which Run already reports as VerdictFail). Advisory
+// verdicts and failures from non-mandatory hooks never reject - they only
+// annotate the phase via the Result slice itself.
+func Aggregate(specs []HookSpec, results []Result) (rejected bool, reason string) {
+	for i, res := range results {
+		if i >= len(specs) {
+			break
+		}
+		if specs[i].Mandatory && res.Verdict == VerdictFail {
+			return true, fmt.Sprintf("mandatory hook %q failed: %s", specs[i].Name, res.Message)
+		}
+	}
+	return false, ""
+}
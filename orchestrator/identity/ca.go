@@ -0,0 +1,150 @@
+// Package identity implements a minimal, in-process SPIFFE workload identity
+// system: a single embedded CA mints short-lived X.509-SVIDs for the
+// orchestrator and for every agent container it spawns, so that
+// orchestrator<->agent gRPC calls can be authenticated with mutual TLS
+// instead of over a bare, unauthenticated connection. It plays the role a
+// real SPIRE server would in production, scoped down to what this
+// orchestrator needs: mint on spawn, verify on dial, expire on a TTL.
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TrustDomain is the SPIFFE trust domain every SVID minted by this process
+// belongs to.
+const TrustDomain = "agent-inc"
+
+// rootCAValidity is how long the self-signed root's own certificate is
+// valid for. This root is discarded on every restart (see CA's doc
+// comment), so a long NotAfter costs nothing - but it must comfortably
+// outlive any single orchestrator process's uptime, since x509 verification
+// checks the root's own validity window on every handshake: a root that
+// expired out from under a long-running process would fail every
+// subsequent mTLS handshake (ExecuteTaskOnAgent's peer verification) for
+// the rest of its life.
+const rootCAValidity = 10 * 365 * 24 * time.Hour
+
+// CA is a self-signed root that mints leaf SVIDs. One CA is created per
+// orchestrator process; it is not persisted, so a restart mints a new trust
+// bundle and invalidates every SVID issued by the previous one.
+type CA struct {
+	mu      sync.Mutex
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// NewCA generates a fresh self-signed root certificate for TrustDomain.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA serial: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "agent-inc SPIFFE root"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(rootCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to self-sign CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse freshly minted CA certificate: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	return &CA{cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// TrustBundlePEM returns the root certificate, PEM-encoded, for agents and
+// orchestrator clients to verify SVIDs against. It's also what
+// /api/identity/bundle hands back for observability.
+func (ca *CA) TrustBundlePEM() []byte {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	out := make([]byte, len(ca.certPEM))
+	copy(out, ca.certPEM)
+	return out
+}
+
+// IssueSVID mints a leaf certificate carrying spiffeID as its sole URI SAN,
+// valid for ttl. spiffeID must be of the form "spiffe://agent-inc/...".
+func (ca *CA) IssueSVID(spiffeID string, ttl time.Duration) (*SVID, error) {
+	if err := validateSPIFFEID(spiffeID); err != nil {
+		return nil, err
+	}
+
+	uri, err := url.Parse(spiffeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SPIFFE ID %q: %w", spiffeID, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SVID key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SVID serial: %w", err)
+	}
+
+	notBefore := time.Now().Add(-time.Minute)
+	notAfter := time.Now().Add(ttl)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: spiffeID},
+		URIs:         []*url.URL{uri},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+
+	ca.mu.Lock()
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	ca.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue SVID for %q: %w", spiffeID, err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SVID key for %q: %w", spiffeID, err)
+	}
+
+	return &SVID{
+		SPIFFEID: spiffeID,
+		CertPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		NotAfter: notAfter,
+	}, nil
+}
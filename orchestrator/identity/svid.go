@@ -0,0 +1,72 @@
+package identity
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SVID is a minted workload identity: a leaf certificate/key pair whose
+// sole URI SAN is SPIFFEID, plus the time it stops being valid.
+type SVID struct {
+	SPIFFEID string
+	CertPEM  []byte
+	KeyPEM   []byte
+	NotAfter time.Time
+}
+
+// Expired reports whether the SVID is no longer valid as of now.
+func (s *SVID) Expired(now time.Time) bool {
+	return now.After(s.NotAfter)
+}
+
+// TLSCertificate loads the SVID's cert/key pair into the shape
+// crypto/tls.Config expects for presenting it as an mTLS identity.
+func (s *SVID) TLSCertificate() (tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(s.CertPEM, s.KeyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load SVID %q as a TLS certificate: %w", s.SPIFFEID, err)
+	}
+	return cert, nil
+}
+
+// ExpertSPIFFEID is the identity a DomainExpert's agent container runs as,
+// scoped to the task and expert role it was spawned for.
+func ExpertSPIFFEID(taskID, role string) string {
+	return fmt.Sprintf("spiffe://%s/task/%s/expert/%s", TrustDomain, taskID, role)
+}
+
+// PlannerSPIFFEID is the identity the lead-agent container generating a
+// task's phased plan runs as.
+func PlannerSPIFFEID(taskID string) string {
+	return fmt.Sprintf("spiffe://%s/task/%s/planner", TrustDomain, taskID)
+}
+
+// NodeSPIFFEID is the identity a tasktree node's agent container runs as,
+// for the legacy (non-phased) execution path.
+func NodeSPIFFEID(taskID, nodeID string) string {
+	return fmt.Sprintf("spiffe://%s/task/%s/node/%s", TrustDomain, taskID, nodeID)
+}
+
+// OrchestratorSPIFFEID is the identity the orchestrator process itself
+// presents as the client of every agent it dials.
+const OrchestratorSPIFFEID = "spiffe://" + TrustDomain + "/orchestrator"
+
+// PoolSPIFFEID is the identity a pre-warmed pool agent runs as before it's
+// handed out to a caller. It carries no task context, since a pooled agent
+// is reset and reused across unrelated tasks rather than spawned for one.
+func PoolSPIFFEID(slot int) string {
+	return fmt.Sprintf("spiffe://%s/pool/agent-%d", TrustDomain, slot)
+}
+
+// validateSPIFFEID rejects anything that isn't a well-formed SPIFFE ID in
+// this trust domain, so a typo'd ID can't mint a certificate that passes
+// peer verification under a path it was never meant to match.
+func validateSPIFFEID(id string) error {
+	prefix := "spiffe://" + TrustDomain + "/"
+	if !strings.HasPrefix(id, prefix) {
+		return fmt.Errorf("SPIFFE ID %q is not in trust domain %q", id, TrustDomain)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package logging provides a context-propagated structured logger, in the
+// style of Docker Swarm's log.WithLogger(ctx, log.G(ctx).WithField(...)):
+// derive a child Logger scoped to a task/phase/node, stash it on the
+// context with WithLogger, and every call downstream that pulls it back out
+// via FromContext inherits its Fields. Every Entry is written as one JSON
+// line to stdout and, if a Sink is installed, handed to it too - the
+// orchestrator uses this to stream entries to subscribed WebSocket clients
+// as a log_line message, giving the UI a live, filterable trace tree
+// instead of requiring users to `docker logs` failed containers by hand.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Fields is a set of structured attributes attached to a Logger or folded
+// into a single Entry, e.g. {"task_id": ..., "phase_id": ..., "node_id":
+// ..., "container_id": ..., "depth": 2}.
+type Fields map[string]interface{}
+
+// Entry is one emitted log line.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"msg"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// Logger carries a fixed set of Fields that every line it emits inherits.
+// A Logger is immutable once created; With derives a child that adds to
+// (rather than mutates) the parent's Fields, so sibling loggers derived
+// from the same parent never see each other's fields.
+type Logger struct {
+	fields Fields
+}
+
+// Root is the process-wide logger with no Fields attached. FromContext
+// falls back to it when ctx carries none.
+var Root = &Logger{}
+
+// With returns a new Logger whose Fields are l's Fields plus extra, extra
+// taking precedence on key collisions.
+func (l *Logger) With(extra Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(extra))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return &Logger{fields: merged}
+}
+
+func (l *Logger) log(level, format string, args ...interface{}) {
+	emit(Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+		Fields:  l.fields,
+	})
+}
+
+func (l *Logger) Info(format string, args ...interface{})  { l.log("info", format, args...) }
+func (l *Logger) Warn(format string, args ...interface{})  { l.log("warn", format, args...) }
+func (l *Logger) Error(format string, args ...interface{}) { l.log("error", format, args...) }
+
+var (
+	sinkMu sync.RWMutex
+	sink   func(Entry)
+)
+
+// SetSink installs the process-wide Entry sink, replacing any previous one.
+// nil (the default) means every Entry only goes to stdout.
+func SetSink(fn func(Entry)) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sink = fn
+}
+
+func emit(entry Entry) {
+	if b, err := json.Marshal(entry); err == nil {
+		os.Stdout.Write(append(b, '\n'))
+	}
+	sinkMu.RLock()
+	fn := sink
+	sinkMu.RUnlock()
+	if fn != nil {
+		fn(entry)
+	}
+}
+
+type ctxKey struct{}
+
+// WithLogger attaches l to ctx; everything downstream that calls
+// FromContext(ctx) inherits l's Fields.
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger attached to ctx via WithLogger, or Root if
+// none was attached.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return Root
+}
+
+// With derives a child of the Logger already attached to ctx (or Root, if
+// none is) by adding extra, and returns a new context carrying that child -
+// a one-call shorthand for WithLogger(ctx, FromContext(ctx).With(extra))
+// for the common case of adding fields and threading the result straight
+// back into a ctx parameter.
+func With(ctx context.Context, extra Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(extra))
+}
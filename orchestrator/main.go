@@ -7,29 +7,122 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"agentic-engineering-system/artifacts"
+	"agentic-engineering-system/dag"
 	"agentic-engineering-system/docker"
+	"agentic-engineering-system/hooks"
+	"agentic-engineering-system/identity"
+	"agentic-engineering-system/logging"
+	"agentic-engineering-system/schedule"
+	"agentic-engineering-system/storage"
 	"agentic-engineering-system/tasks"
+	"agentic-engineering-system/tasks/pool"
 	"agentic-engineering-system/tasktree"
+	"agentic-engineering-system/tracing"
 	"agentic-engineering-system/websocket"
 
 	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Global state for the orchestrator
 var (
 	dockerManager *docker.Manager
+	artifactStore *artifacts.Store
 	currentTasks  = make(map[string]*TaskExecution)
 	tasksMutex    sync.RWMutex
-	db            *bbolt.DB
+	taskStore     storage.Interface
 	wsHub         *websocket.Hub
-	sseClients    = make(map[string]chan string)
-	sseMutex      sync.RWMutex
+	hookRunner    *hooks.Runner
 )
 
+// tasksKeyPrefix namespaces every TaskExecution in taskStore; it also
+// doubles as the prefix watch key for the global wsHub broadcaster.
+const tasksKeyPrefix = "tasks/"
+
+func taskKey(taskID string) string {
+	return tasksKeyPrefix + taskID
+}
+
+// phaseWorkerPoolSize bounds how many ProjectPhases run concurrently once
+// their DAG dependencies are satisfied. Without a bound, a plan with a wide
+// fan-out (e.g. ten independent phases) would spawn agent containers for
+// every one of them at once.
+const phaseWorkerPoolSize = 4
+
+// defaultMaxParallelSubtasks is how many tasktree sub-task containers may
+// run concurrently across an entire task tree, overridable via
+// AGENT_INC_MAX_PARALLEL_SUBTASKS. maxSubtasksPerNode further caps how many
+// of a single node's own children run at once, so one wide delegation can't
+// alone consume the whole global budget and starve sibling branches.
+const (
+	defaultMaxParallelSubtasks = 16
+	maxSubtasksPerNode         = 8
+)
+
+// subtaskSemaphore gates dm.SpawnAgent calls across the whole tasktree
+// execution path (see executeNode), replacing the old fixed
+// time.Sleep(2*time.Second) stagger with real backpressure: a wide plan
+// (20+ experts) now queues past the limit instead of starting every
+// container at once.
+var subtaskSemaphore = make(chan struct{}, maxParallelSubtasks())
+
+func maxParallelSubtasks() int {
+	v := os.Getenv("AGENT_INC_MAX_PARALLEL_SUBTASKS")
+	if v == "" {
+		return defaultMaxParallelSubtasks
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("⚠️ invalid AGENT_INC_MAX_PARALLEL_SUBTASKS=%q, using default %d", v, defaultMaxParallelSubtasks)
+		return defaultMaxParallelSubtasks
+	}
+	return n
+}
+
+// defaultReconcileIntervalSeconds is how often reconcileTreeSnapshots
+// re-persists every live task's Tree, overridable via
+// AGENT_INC_RECONCILE_INTERVAL_SECONDS.
+const defaultReconcileIntervalSeconds = 30
+
+func reconcileInterval() time.Duration {
+	v := os.Getenv("AGENT_INC_RECONCILE_INTERVAL_SECONDS")
+	if v == "" {
+		return defaultReconcileIntervalSeconds * time.Second
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("⚠️ invalid AGENT_INC_RECONCILE_INTERVAL_SECONDS=%q, using default %d", v, defaultReconcileIntervalSeconds)
+		return defaultReconcileIntervalSeconds * time.Second
+	}
+	return time.Duration(n) * time.Second
+}
+
+type fastFailKey struct{}
+
+// withFastFail attaches execution.FastFail to ctx so executeNode, arbitrarily
+// deep in a delegation tree, can read it back without threading an extra
+// parameter through every recursive call.
+func withFastFail(ctx context.Context, fastFail bool) context.Context {
+	return context.WithValue(ctx, fastFailKey{}, fastFail)
+}
+
+// fastFailFromContext returns the FastFail flag attached via withFastFail,
+// or false if none was attached.
+func fastFailFromContext(ctx context.Context) bool {
+	fastFail, _ := ctx.Value(fastFailKey{}).(bool)
+	return fastFail
+}
+
 type TaskExecution struct {
 	ID                   string             `json:"id"`
 	Task                 string             `json:"task"`
@@ -46,28 +139,87 @@ type TaskExecution struct {
 	RequiresUserApproval bool               `json:"requiresUserApproval"`
 	CreatedAt            time.Time          `json:"createdAt"`
 	UpdatedAt            time.Time          `json:"updatedAt"`
+	TaskSpan             trace.Span         `json:"-"` // Root OTEL span for this execution; ended when the task reaches a terminal status.
+	// ResourceVersion is set by taskStore on every persisted write; used for optimistic-concurrency CAS.
+	ResourceVersion int64 `json:"resourceVersion"`
+	// Targets optionally restricts execution to these phase IDs and their
+	// transitive Dependencies, matching dag.Ancestors; every phase outside
+	// that set is recorded "omitted" instead of run. Empty means run every
+	// phase in the plan.
+	Targets []string `json:"targets,omitempty"`
+	// Finally lists phases that always run after Phases finishes, fails, or
+	// is cancelled, mirroring Tekton's pipeline `finally` semantics -
+	// typically postmortems, resource cleanup instructions, or notification
+	// digests. A failure here never overwrites Status or Error; it only
+	// shows up in FinallyResults.
+	Finally []ProjectPhase `json:"finally,omitempty"`
+	// FinallyResults holds each finally-phase expert's output, keyed
+	// "<phaseID>/<role>", plus a "<phaseID>/error" entry for any finally
+	// phase that didn't complete cleanly.
+	FinallyResults map[string]string `json:"finallyResults,omitempty"`
+	// FastFail applies to the tree-based execution fallback
+	// (executeTaskWithTree/executeNode): when true, the first sub-task that
+	// fails or errors cancels its siblings' shared context instead of
+	// waiting for all of them to finish, and any sibling that hasn't
+	// started yet is recorded "omitted" rather than run. Propagated onto
+	// Context via withFastFail so every recursive executeNode call can see
+	// it without an extra parameter.
+	FastFail bool `json:"fastFail,omitempty"`
+	// TreeSnapshot is a periodically-refreshed, JSON-marshalable copy of
+	// Tree (which itself is never serialized - it holds live *sync.Mutex
+	// nodes). saveTaskState repopulates it from Tree.Snapshot() before every
+	// persist, and loadTaskState/loadAllTasks rebuild Tree from it via
+	// tasktree.Tree.Restore on the other end, so an orchestrator restart
+	// mid-run doesn't lose the in-progress delegation tree - see
+	// reconcileTreeSnapshots.
+	TreeSnapshot map[string]tasktree.NodeSnapshot `json:"treeSnapshot,omitempty"`
 }
 
 type ProjectPhase struct {
-	ID           string            `json:"id"`
-	Name         string            `json:"name"`
-	Description  string            `json:"description"`
-	Status       string            `json:"status"` // "pending", "approved", "running", "completed", "rejected"
-	Experts      []DomainExpert    `json:"experts"`
-	Results      map[string]string `json:"results,omitempty"`
-	StartTime    *time.Time        `json:"startTime,omitempty"`
-	EndTime      *time.Time        `json:"endTime,omitempty"`
-	Approved     bool              `json:"approved"`
-	UserFeedback string            `json:"userFeedback,omitempty"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	// Dependencies lists the IDs of phases that must reach "completed"
+	// before this phase becomes eligible to run. Phases with no
+	// Dependencies (or whose Dependencies are all already satisfied) run
+	// concurrently, bounded by phaseWorkerPoolSize, instead of waiting for
+	// every earlier phase in plan order the way strictly-linear progression
+	// used to require.
+	Dependencies    []string          `json:"dependencies,omitempty"`
+	Status          string            `json:"status"` // "pending", "running", "awaiting_approval", "approved", "completed", "rejected", "omitted"
+	Experts         []DomainExpert    `json:"experts"`
+	Results         map[string]string `json:"results,omitempty"`
+	StartTime       *time.Time        `json:"startTime,omitempty"`
+	EndTime         *time.Time        `json:"endTime,omitempty"`
+	Approved        bool              `json:"approved"`
+	UserFeedback    string            `json:"userFeedback,omitempty"`
+	ManifestVersion int               `json:"manifestVersion,omitempty"` // Signed artifacts.Manifest version backing Results, once sealed.
+	ResourceVersion int64             `json:"resourceVersion,omitempty"` // The owning TaskExecution's ResourceVersion as of the last time this phase was persisted.
+	// PreApprovalHooks lists external "run task" gates - policy engines,
+	// cost estimators, security scanners - that sealAndGatePhase fires
+	// after this phase completes and before the approval broadcast. A
+	// failing mandatory hook auto-rejects the phase instead of ever
+	// reaching phase_awaiting_approval.
+	PreApprovalHooks []hooks.HookSpec `json:"preApprovalHooks,omitempty"`
+	// HookResults holds each hook's verdict from the most recent run,
+	// surfaced in the phase_awaiting_approval payload.
+	HookResults []hooks.Result `json:"hookResults,omitempty"`
+	span        trace.Span     // OTEL span for this phase; lives until approved/rejected.
+	ctx             context.Context   // carries span above; parent for this phase's expert spans.
+	// approvalCh is signaled exactly once by handlePhaseApproval with the
+	// user's decision; sealAndGatePhase blocks on it when the task requires
+	// approval. Allocated when the phase starts running, nil until then.
+	approvalCh chan bool
 }
 
 type DomainExpert struct {
-	Role      string `json:"role"`
-	Expertise string `json:"expertise"`
-	Persona   string `json:"persona"`
-	Task      string `json:"task"`
-	Status    string `json:"status"` // "pending", "running", "completed", "failed"
-	Result    string `json:"result,omitempty"`
+	Role                 string   `json:"role"`
+	Expertise            string   `json:"expertise"`
+	Persona              string   `json:"persona"`
+	Task                 string   `json:"task"`
+	Status               string   `json:"status"` // "pending", "running", "completed", "failed"
+	Result               string   `json:"result,omitempty"`
+	RequiredCapabilities []string `json:"requiredCapabilities,omitempty"` // Tool names (e.g. "shell", "python") the spawned agent must advertise.
 }
 
 type PhaseApprovalRequest struct {
@@ -79,6 +231,13 @@ type PhaseApprovalRequest struct {
 
 type TaskRequest struct {
 	Task string `json:"task"`
+	// Targets optionally names phase IDs to run, for re-targeting a DAG
+	// partially; see TaskExecution.Targets. Only meaningful once a plan
+	// with those IDs already exists, so this is normally left empty on
+	// initial submission.
+	Targets []string `json:"targets,omitempty"`
+	// FastFail opts this task into TaskExecution.FastFail.
+	FastFail bool `json:"fastFail,omitempty"`
 }
 
 type TaskResponse struct {
@@ -86,74 +245,147 @@ type TaskResponse struct {
 	Status string `json:"status"`
 }
 
-// BoltDB Functions
+// saveTaskState persists execution through taskStore's GuaranteedUpdate
+// instead of a blind Put, so two goroutines racing to save the same task
+// (e.g. executeDomainExpert and checkPhaseCompletion finishing back to
+// back) CAS against each other rather than one silently clobbering the
+// other's write. Because GuaranteedUpdate may call tryUpdate more than once
+// (it re-reads current and retries on every conflicting commit), tryUpdate
+// actually reconciles against current instead of ignoring it: it takes
+// execution's top-level fields as authoritative (they reflect whatever this
+// goroutine just mutated under tasksMutex), but per phase it keeps whichever
+// of execution's or current's copy has the higher ResourceVersion - so a
+// phase another writer already persisted past what this in-memory execution
+// has seen is never regressed by this call's retry. On success every
+// phase's ResourceVersion is stamped with the version that was actually
+// persisted.
 func saveTaskState(execution *TaskExecution) error {
-	return db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte("tasks"))
-		encoded, err := json.Marshal(execution)
-		if err != nil {
-			return fmt.Errorf("failed to serialize task %s: %w", execution.ID, err)
+	ctx, span := tracing.StartDBSpan(context.Background(), "save_task", execution.ID)
+	defer span.End()
+
+	if execution.Tree != nil {
+		execution.TreeSnapshot = execution.Tree.Snapshot()
+	}
+	_, rv, err := taskStore.GuaranteedUpdate(ctx, taskKey(execution.ID), func(current []byte) ([]byte, error) {
+		if current == nil {
+			return json.Marshal(execution)
+		}
+		var stored TaskExecution
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return nil, fmt.Errorf("failed to deserialize stored task %s for reconcile: %w", execution.ID, err)
 		}
-		return b.Put([]byte(execution.ID), encoded)
+		merged := *execution
+		merged.Phases = mergePhases(execution.Phases, stored.Phases)
+		return json.Marshal(&merged)
 	})
+	if err != nil {
+		return fmt.Errorf("failed to persist task %s: %w", execution.ID, err)
+	}
+	execution.ResourceVersion = rv
+	for i := range execution.Phases {
+		execution.Phases[i].ResourceVersion = rv
+	}
+	return nil
 }
 
-func loadTaskState(taskID string) (*TaskExecution, error) {
-	var execution TaskExecution
-	err := db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte("tasks"))
-		data := b.Get([]byte(taskID))
-		if data == nil {
-			return fmt.Errorf("task %s not found in DB", taskID)
+// mergePhases reconciles saveTaskState's in-memory phases against the
+// phases most recently committed to the store, matched by ID: for a phase
+// present in both, the one with the higher ResourceVersion wins, so this
+// call never overwrites a phase another writer already persisted a newer
+// copy of. A phase present in only one side is kept as-is (covers a phase
+// this call added that isn't in the store yet).
+func mergePhases(ours, stored []ProjectPhase) []ProjectPhase {
+	storedByID := make(map[string]ProjectPhase, len(stored))
+	for _, p := range stored {
+		storedByID[p.ID] = p
+	}
+
+	merged := make([]ProjectPhase, len(ours))
+	for i, p := range ours {
+		if sp, ok := storedByID[p.ID]; ok && sp.ResourceVersion > p.ResourceVersion {
+			merged[i] = sp
+		} else {
+			merged[i] = p
 		}
-		if err := json.Unmarshal(data, &execution); err != nil {
-			return fmt.Errorf("failed to deserialize task %s: %w", taskID, err)
+		delete(storedByID, p.ID)
+	}
+	for _, sp := range stored {
+		if _, stillPresent := storedByID[sp.ID]; stillPresent {
+			merged = append(merged, sp)
 		}
-		return nil
-	})
+	}
+	return merged
+}
+
+func loadTaskState(taskID string) (*TaskExecution, error) {
+	ctx, span := tracing.StartDBSpan(context.Background(), "get_task", taskID)
+	defer span.End()
+	data, rv, err := taskStore.Get(ctx, taskKey(taskID))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("task %s not found in store: %w", taskID, err)
 	}
+	var execution TaskExecution
+	if err := json.Unmarshal(data, &execution); err != nil {
+		return nil, fmt.Errorf("failed to deserialize task %s: %w", taskID, err)
+	}
+	execution.ResourceVersion = rv
+	rehydrateTree(&execution)
 	return &execution, nil
 }
 
 func loadAllTasks() ([]*TaskExecution, error) {
+	entries, err := taskStore.List(context.Background(), tasksKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
 	var tasks []*TaskExecution
-	err := db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte("tasks"))
-		return b.ForEach(func(k, v []byte) error {
-			var execution TaskExecution
-			if err := json.Unmarshal(v, &execution); err != nil {
-				log.Printf("Warning: Failed to deserialize task %s: %v", string(k), err)
-				return nil // Continue with other tasks
-			}
-			tasks = append(tasks, &execution)
-			return nil
-		})
-	})
-	return tasks, err
-}
-
-// Broadcast updates to WebSocket clients (similar to SSE concept)
-func broadcastUpdate(taskID string) {
-	execution, err := loadTaskState(taskID)
-	if err == nil {
-		if wsHub != nil {
-			wsHub.BroadcastMessage("task_updated", execution, taskID, "")
+	for _, entry := range entries {
+		var execution TaskExecution
+		if err := json.Unmarshal(entry.Value, &execution); err != nil {
+			log.Printf("Warning: Failed to deserialize task %s: %v", entry.Key, err)
+			continue
 		}
+		execution.ResourceVersion = entry.ResourceVersion
+		rehydrateTree(&execution)
+		tasks = append(tasks, &execution)
+	}
+	return tasks, nil
+}
 
-		// Also broadcast to SSE clients
-		sseMutex.RLock()
-		clientChan, ok := sseClients[taskID]
-		sseMutex.RUnlock()
+// rehydrateTree rebuilds execution.Tree from execution.TreeSnapshot (if any
+// was persisted) via tasktree.Tree.Restore, so a task loaded back from
+// taskStore - typically during startup reconciliation - has a usable Tree
+// again instead of the nil left by Tree's `json:"-"` tag. Nodes that were
+// genuinely in flight when the snapshot was taken come back "interrupted".
+func rehydrateTree(execution *TaskExecution) {
+	if len(execution.TreeSnapshot) == 0 {
+		return
+	}
+	execution.Tree = tasktree.NewTree()
+	execution.Tree.Restore(execution.TreeSnapshot)
+}
 
-		if ok {
-			jsonData, _ := json.Marshal(execution)
-			select {
-			case clientChan <- string(jsonData):
-			default:
-				// Channel is full or closed, skip
-			}
+// watchTaskUpdates forwards every taskStore write, across all tasks, to
+// wsHub as a generic "task_updated" message. It replaces the old ad-hoc
+// broadcastUpdate function: saveTaskState no longer needs to know who's
+// listening, since every write already goes through taskStore and shows up
+// here automatically.
+func watchTaskUpdates(ctx context.Context) {
+	events, err := taskStore.Watch(ctx, tasksKeyPrefix, 0)
+	if err != nil {
+		log.Printf("⚠️ Failed to start task update watch: %v", err)
+		return
+	}
+	for ev := range events {
+		if ev.Type == storage.EventDeleted {
+			continue
+		}
+		var execution TaskExecution
+		if err := json.Unmarshal(ev.Value, &execution); err != nil {
+			continue
+		}
+		if wsHub != nil {
+			wsHub.BroadcastMessage("task_updated", &execution, execution.ID, "")
 		}
 	}
 }
@@ -166,37 +398,39 @@ func handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	messageChan := make(chan string, 10) // Buffered channel
+	unregister := registerSSEClient(taskID, r.RemoteAddr)
+	defer unregister()
 
-	sseMutex.Lock()
-	sseClients[taskID] = messageChan
-	sseMutex.Unlock()
+	ctx := r.Context()
 
-	defer func() {
-		sseMutex.Lock()
-		delete(sseClients, taskID)
-		sseMutex.Unlock()
-		close(messageChan)
-	}()
-
-	// Send initial state
-	if execution, err := loadTaskState(taskID); err == nil {
-		if jsonData, err := json.Marshal(execution); err == nil {
-			fmt.Fprintf(w, "data: %s\n\n", string(jsonData))
-			if f, ok := w.(http.Flusher); ok {
-				f.Flush()
-			}
+	// Send initial state.
+	if data, _, err := taskStore.Get(ctx, taskKey(taskID)); err == nil {
+		fmt.Fprintf(w, "data: %s\n\n", string(data))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
 		}
 	}
 
+	events, err := taskStore.Watch(ctx, taskKey(taskID), 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	for {
 		select {
-		case msg := <-messageChan:
-			fmt.Fprintf(w, "data: %s\n\n", msg)
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type == storage.EventDeleted {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", string(ev.Value))
 			if f, ok := w.(http.Flusher); ok {
 				f.Flush()
 			}
-		case <-r.Context().Done():
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -214,23 +448,34 @@ func main() {
 		port = "8080"
 	}
 
-	// Initialize BoltDB
+	// Initialize the task store. BoltStore is the single-node default;
+	// swapping in storage.NewEtcdStore here is the whole migration needed
+	// to run multiple orchestrator replicas against shared state, since
+	// every caller only ever sees the storage.Interface.
 	var err error
-	db, err = bbolt.Open("orchestrator.db", 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	boltDB, err := bbolt.Open("orchestrator.db", 0600, &bbolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
 		log.Fatalf("FATAL: Could not open database: %v", err)
 	}
-	defer db.Close()
-
-	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("tasks"))
-		return err
-	})
+	taskStore, err = storage.NewBoltStore(boltDB, "tasks")
 	if err != nil {
-		log.Fatalf("FATAL: Could not create tasks bucket: %v", err)
+		log.Fatalf("FATAL: Could not initialize task storage: %v", err)
 	}
+	defer taskStore.Close()
 
-	log.Printf("‚úÖ BoltDB initialized successfully")
+	log.Printf("‚úÖ Task storage initialized successfully")
+
+	// Wire up OpenTelemetry before anything starts spawning agents or
+	// serving requests, so no span gets missed.
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatalf("FATAL: Could not initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("‚ö†Ô∏è Failed to shut down tracing cleanly: %v", err)
+		}
+	}()
 
 	// Load existing tasks from database
 	loadTasksFromDB()
@@ -238,25 +483,72 @@ func main() {
 	// Initialize WebSocket hub
 	wsHub = websocket.NewHub()
 	go wsHub.Run()
+	go watchTaskUpdates(context.Background())
+	go reconcileTreeSnapshots(context.Background(), reconcileInterval())
+
+	// Stream every structured log entry to subscribed WebSocket clients as
+	// a log_line message, filtered by whatever task_id/phase_id or node_id
+	// it carries, in addition to the JSON line logging already writes to
+	// stdout.
+	logging.SetSink(func(entry logging.Entry) {
+		if wsHub == nil {
+			return
+		}
+		taskID, _ := entry.Fields["task_id"].(string)
+		phaseID, _ := entry.Fields["phase_id"].(string)
+		if phaseID == "" {
+			phaseID, _ = entry.Fields["node_id"].(string)
+		}
+		wsHub.BroadcastMessage("log_line", entry, taskID, phaseID)
+	})
 
 	// Initialize Docker manager
 	ctx := context.Background()
-	dockerManager, err = docker.NewManager(ctx)
+	dockerManager, err = docker.NewManager(ctx, docker.DefaultRuntimeConfig())
 	if err != nil {
 		log.Fatalf("Failed to create docker manager: %v", err)
 	}
 	defer dockerManager.CleanupAllAgents()
 
-	// Setup HTTP routes
-	http.HandleFunc("/api/task", enableCORS(handleTask))
+	// Initialize the phase artifact store. Its signing key lives only for
+	// this process's lifetime, same as dockerManager's identity CA.
+	artifactStore, err = artifacts.NewStore("artifacts")
+	if err != nil {
+		log.Fatalf("Failed to create artifact store: %v", err)
+	}
+
+	// Initialize the pre-approval hook runner. callbackBaseURL must be
+	// reachable from wherever PreApprovalHooks' URLs live (an OPA sidecar, a
+	// cost-estimator service, etc.), so it's overridable for deployments
+	// where this process isn't addressable as localhost.
+	callbackBaseURL := os.Getenv("AGENT_INC_HOOK_CALLBACK_BASE_URL")
+	if callbackBaseURL == "" {
+		callbackBaseURL = "http://localhost:" + port + "/hooks/callback"
+	}
+	hookRunner = hooks.NewRunner(callbackBaseURL)
+
+	// Setup HTTP routes. Handlers that sit on the critical path for a
+	// TaskExecution's trace (task submission, phase approval, and the SSE
+	// stream) are wrapped with otelhttp so an inbound traceparent header is
+	// honored instead of always starting a disconnected trace.
+	http.Handle("/api/task", otelhttp.NewHandler(enableCORS(handleTask), "handleTask"))
 	http.HandleFunc("/api/task/", enableCORS(handleTaskStatus))
 	http.HandleFunc("/api/task/{taskId}/phase/{phaseId}", enableCORS(handlePhaseDetails))
-	http.HandleFunc("/api/task/{taskId}/events", enableCORS(handleTaskEvents))
-	http.HandleFunc("/api/phases/approve", enableCORS(handlePhaseApproval))
+	http.HandleFunc("/api/task/{taskId}/trace", enableCORS(handleTaskTrace))
+	http.Handle("/api/task/{taskId}/events", otelhttp.NewHandler(enableCORS(handleTaskEvents), "handleTaskEvents"))
+	http.Handle("/api/phases/approve", otelhttp.NewHandler(enableCORS(handlePhaseApproval), "handlePhaseApproval"))
 	http.HandleFunc("/api/phase/", enableCORS(handlePhaseResults))
+	http.HandleFunc("/api/phase/{taskId}/{phaseId}/artifact/{role}", enableCORS(handleArtifactStream))
+	http.HandleFunc("/api/task/{taskId}/phase/{phaseId}/rollback", enableCORS(handlePhaseRollback))
+	http.HandleFunc("/api/identity/bundle", enableCORS(handleIdentityBundle))
+	http.HandleFunc("/api/agents/capabilities", enableCORS(handleAgentCapabilities))
+	http.HandleFunc("/hooks/callback/", enableCORS(handleHookCallback))
 	http.HandleFunc("/ws", wsHub.HandleWebSocket)
 	http.HandleFunc("/health", handleHealth)
 
+	// Operator debug/introspection surface, gated behind DEBUG_TOKEN.
+	http.Handle("/debug/", requireDebugToken(newDebugMux()))
+
 	// Serve static files for the UI
 	fs := http.FileServer(http.Dir("./ui/dist"))
 	http.Handle("/", http.StripPrefix("/", fs))
@@ -269,6 +561,11 @@ func main() {
 	log.Println("   GET  /api/task/{id} - Get task status")
 	log.Println("   GET  /api/phase/{taskId}/{phaseId} - Get phase results")
 	log.Println("   POST /api/phases/approve - Approve/reject phase")
+	log.Println("   POST /hooks/callback/{token} - Async pre-approval hook verdict callback")
+	log.Println("   GET  /api/agents/capabilities - Advertised capabilities for every running agent")
+	log.Println("   POST /api/task/{id}/phase/{pid}/rollback - Roll back to the previous signed phase manifest")
+	log.Println("   GET  /api/phase/{tid}/{pid}/artifact/{role} - Stream a phase artifact's raw blob")
+	log.Println("   GET  /api/task/{id}/trace - Get recorded OTEL span tree")
 	log.Println("   WS   /ws - WebSocket for real-time updates")
 	log.Println("   GET  /health - Health check")
 
@@ -287,11 +584,70 @@ func loadTasksFromDB() {
 	tasksMutex.Lock()
 	defer tasksMutex.Unlock()
 
+	interrupted := 0
 	for _, task := range tasks {
 		currentTasks[task.ID] = task
+		if task.Tree != nil {
+			interrupted += task.Tree.CountByStatus()["interrupted"]
+		}
 	}
 
-	log.Printf("‚úÖ Loaded %d tasks from database", len(tasks))
+	log.Printf("‚úÖ Loaded %d tasks from database (%d sub-tasks interrupted by restart)", len(tasks), interrupted)
+}
+
+// reconcileTreeSnapshots is the background half of tree durability: every
+// interval, it re-persists each live task's current Tree via saveTaskState
+// so TreeSnapshot in the store never drifts far behind an in-progress
+// delegation run, the way it would if the only writes were the handful of
+// saveTaskState calls at task start/approval/completion. saveTaskState's
+// writes themselves are safe to run from multiple orchestrator instances -
+// taskStore.GuaranteedUpdate is a compare-and-swap retry loop, not a blind
+// write - but that's necessary, not sufficient: this repo's shipped default
+// store is a bbolt.Open'd BoltStore (see main()'s comment above it), and
+// bbolt's own file lock means a second orchestrator process pointed at the
+// same orchestrator.db fails to even open the file, let alone run this
+// reconciler concurrently against it. Actually scaling this out - including
+// this function - needs the storage.NewEtcdStore backend swapped in, as
+// well as real per-node conflict/ownership semantics (e.g. the SELECT ...
+// FOR UPDATE SKIP LOCKED row-claiming a Postgres-backed reconciler would
+// use) beyond what this snapshot-level CAS alone provides.
+func reconcileTreeSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tasksMutex.RLock()
+			live := make([]*TaskExecution, 0, len(currentTasks))
+			for _, task := range currentTasks {
+				switch task.Status {
+				case "completed", "error", "failed":
+					continue
+				}
+				if task.Tree != nil {
+					live = append(live, task)
+				}
+			}
+			tasksMutex.RUnlock()
+
+			// saveTaskState reads execution's fields (and, for Tree != nil,
+			// takes a fresh Tree.Snapshot) to marshal them, so it must run
+			// under the same tasksMutex every other call site holds across it -
+			// otherwise it races executeDomainExpert/handlePhaseApproval/etc.
+			// mutating those same fields concurrently.
+			for _, task := range live {
+				tasksMutex.Lock()
+				err := saveTaskState(task)
+				tasksMutex.Unlock()
+				if err != nil {
+					log.Printf("⚠️ reconcile: failed to checkpoint tree for task %s: %v", task.ID, err)
+				}
+			}
+		}
+	}
 }
 
 func saveTaskToDB(task *TaskExecution) {
@@ -331,7 +687,12 @@ func handleTask(w http.ResponseWriter, r *http.Request) {
 
 		// Create new task execution
 		taskID := fmt.Sprintf("task_%d", time.Now().Unix())
-		ctx, cancel := context.WithCancel(context.Background())
+
+		// Root span for the whole TaskExecution. r.Context() carries
+		// whatever trace context otelhttp extracted from the inbound
+		// request, so a caller-provided traceparent is honored.
+		taskCtx, taskSpan := tracing.StartTaskSpan(r.Context(), taskID, req.Task)
+		ctx, cancel := context.WithCancel(taskCtx)
 
 		now := time.Now()
 		execution := &TaskExecution{
@@ -344,6 +705,9 @@ func handleTask(w http.ResponseWriter, r *http.Request) {
 			RequiresUserApproval: true,
 			CreatedAt:            now,
 			UpdatedAt:            now,
+			TaskSpan:             taskSpan,
+			Targets:              req.Targets,
+			FastFail:             req.FastFail,
 		}
 
 		tasksMutex.Lock()
@@ -352,7 +716,7 @@ func handleTask(w http.ResponseWriter, r *http.Request) {
 
 		// Save the initial state to the database
 		if err := saveTaskState(execution); err != nil {
-			log.Printf("ERROR: Failed to save initial state for task %s: %v", taskID, err)
+			logging.Root.With(logging.Fields{"task_id": taskID}).Error("Failed to save initial state: %v", err)
 			http.Error(w, "Failed to persist task", http.StatusInternalServerError)
 			return
 		}
@@ -390,6 +754,10 @@ func handleTask(w http.ResponseWriter, r *http.Request) {
 				RequiresUserApproval: task.RequiresUserApproval,
 				CreatedAt:            task.CreatedAt,
 				UpdatedAt:            task.UpdatedAt,
+				Targets:              task.Targets,
+				Finally:              task.Finally,
+				FinallyResults:       task.FinallyResults,
+				FastFail:             task.FastFail,
 			}
 			tasks = append(tasks, taskCopy)
 		}
@@ -447,6 +815,10 @@ func handleTaskStatus(w http.ResponseWriter, r *http.Request) {
 		RequiresUserApproval: execution.RequiresUserApproval,
 		CreatedAt:            execution.CreatedAt,
 		UpdatedAt:            execution.UpdatedAt,
+		Targets:              execution.Targets,
+		Finally:              execution.Finally,
+		FinallyResults:       execution.FinallyResults,
+		FastFail:             execution.FastFail,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -545,6 +917,147 @@ func handlePhaseResults(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleTaskTrace returns every span recorded for a task's execution as
+// JSON, so the UI can render a trace tree even when no OTLP/Zipkin
+// collector is configured to receive one.
+func handleTaskTrace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID := r.PathValue("taskId")
+	spans := tracing.Trace(taskID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spans)
+}
+
+// handleIdentityBundle exposes the PEM-encoded root certificate every agent
+// and orchestrator SVID is verified against, so an operator can confirm
+// which trust domain/CA a running orchestrator is issuing identities under
+// without shelling into a container.
+func handleIdentityBundle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(dockerManager.TrustBundlePEM())
+}
+
+// handleArtifactStream streams the raw blob backing one expert's result in
+// the latest signed manifest for a phase, so a caller can fetch exactly the
+// bytes the manifest vouches for rather than the JSON-embedded copy in
+// phase.Results.
+func handleArtifactStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID := r.PathValue("taskId")
+	phaseID := r.PathValue("phaseId")
+	role := r.PathValue("role")
+
+	manifest, ok := artifactStore.Latest(taskID, phaseID)
+	if !ok {
+		http.Error(w, "No signed manifest for this phase", http.StatusNotFound)
+		return
+	}
+
+	var artifact *artifacts.Artifact
+	for i := range manifest.Artifacts {
+		if manifest.Artifacts[i].Role == role {
+			artifact = &manifest.Artifacts[i]
+			break
+		}
+	}
+	if artifact == nil {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	content, err := artifactStore.GetArtifact(artifact.Digest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Artifact-Digest", "sha256:"+artifact.Digest)
+	w.Write(content)
+}
+
+// handlePhaseRollback restores the previous signed manifest version for a
+// phase as a new, higher version (the manifest history is append-only), and
+// points the phase at it. It does not touch phase.Status: an operator who
+// rolls back a completed/approved phase is expected to re-trigger approval
+// explicitly if that's what the rollback was for.
+func handlePhaseRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID := r.PathValue("taskId")
+	phaseID := r.PathValue("phaseId")
+
+	tasksMutex.Lock()
+	defer tasksMutex.Unlock()
+
+	execution, exists := currentTasks[taskID]
+	if !exists {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	var phase *ProjectPhase
+	for i := range execution.Phases {
+		if execution.Phases[i].ID == phaseID {
+			phase = &execution.Phases[i]
+			break
+		}
+	}
+	if phase == nil {
+		http.Error(w, "Phase not found", http.StatusNotFound)
+		return
+	}
+
+	manifest, err := artifactStore.Rollback(taskID, phaseID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	phase.ManifestVersion = manifest.Version
+	execution.UpdatedAt = time.Now()
+
+	if err := saveTaskState(execution); err != nil {
+		logging.FromContext(execution.Context).With(logging.Fields{"phase_id": phaseID}).Error("Failed to save phase rollback: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"manifest": manifest,
+		"phase":    phase,
+	})
+}
+
+// handleAgentCapabilities exposes every currently running agent's advertised
+// capability set, keyed by container ID, so the UI can show per-expert
+// exactly which tools its spawned agent was allowed to use.
+func handleAgentCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dockerManager.AllCapabilities())
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
 		"status":    "healthy",
@@ -566,6 +1079,13 @@ func updateTaskStatus(execution *TaskExecution, status string, result, error str
 	if error != "" {
 		execution.Error = error
 	}
+	if execution.TaskSpan != nil && (status == "completed" || status == "failed" || status == "error") {
+		if error != "" {
+			execution.TaskSpan.SetStatus(codes.Error, error)
+		}
+		execution.TaskSpan.End()
+		execution.TaskSpan = nil
+	}
 	tasksMutex.Unlock()
 
 	// Save to database
@@ -582,6 +1102,43 @@ func updateTaskStatus(execution *TaskExecution, status string, result, error str
 	}
 }
 
+// hookCallbackRequest is the body an async pre-approval hook POSTs back to
+// its own CallbackURL once it has reached a verdict.
+type hookCallbackRequest struct {
+	Verdict hooks.Verdict `json:"verdict"`
+	Message string        `json:"message"`
+}
+
+// handleHookCallback delivers an async pre-approval hook's verdict to the
+// sealAndGatePhase call still blocked waiting on it. token is everything
+// after "/hooks/callback/" and must match the CallbackURL the hook was
+// given in its original Payload.
+func handleHookCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, "/hooks/callback/")
+	if token == "" {
+		http.Error(w, "missing callback token", http.StatusBadRequest)
+		return
+	}
+
+	var req hookCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if hookRunner == nil || !hookRunner.Resolve(token, req.Verdict, req.Message) {
+		http.Error(w, "no hook awaiting this callback (already resolved, timed out, or unknown)", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func handlePhaseApproval(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -618,14 +1175,58 @@ func handlePhaseApproval(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if phase.Status != "awaiting_approval" {
+		tasksMutex.Unlock()
+		http.Error(w, fmt.Sprintf("phase %s is not awaiting approval (status: %s)", req.PhaseID, phase.Status), http.StatusConflict)
+		return
+	}
+
+	// Before an approval can unblock this phase's dependents, re-verify the
+	// phase's signed manifest and every artifact digest it lists: a bit flip
+	// in BoltDB or a tampered blob on disk must not silently flow into a
+	// downstream phase.
+	if req.Approved && phase.ManifestVersion > 0 {
+		manifest, ok := artifactStore.Latest(req.TaskID, phase.ID)
+		if !ok || manifest.Version != phase.ManifestVersion {
+			tasksMutex.Unlock()
+			http.Error(w, "phase manifest not found", http.StatusInternalServerError)
+			return
+		}
+		if err := artifactStore.Verify(manifest); err != nil {
+			phase.Status = "rejected"
+			errMsg := fmt.Sprintf("phase artifact integrity check failed: %v", err)
+			logging.FromContext(execution.Context).With(logging.Fields{"phase_id": req.PhaseID}).Error("Phase rejected: %v", err)
+			approvalCh := phase.approvalCh
+
+			tasksMutex.Unlock()
+			saveTaskToDB(execution)
+			approvalCh <- false
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   errMsg,
+				"phase":   phase,
+			})
+			return
+		}
+	}
+
 	// Update phase approval status
 	phase.Approved = req.Approved
 	phase.UserFeedback = req.UserFeedback
 	execution.UpdatedAt = time.Now()
+	approvalCh := phase.approvalCh
 
 	if req.Approved {
 		phase.Status = "approved"
-		log.Printf("‚úÖ [%s] Phase %s approved by user", req.TaskID, req.PhaseID)
+		logging.FromContext(execution.Context).With(logging.Fields{"phase_id": req.PhaseID}).Info("Phase approved by user")
+
+		if phase.span != nil {
+			phase.span.AddEvent("phase.approved")
+			phase.span.End()
+			phase.span = nil
+		}
 
 		// Broadcast phase approval via WebSocket
 		if wsHub != nil {
@@ -635,25 +1236,18 @@ func handlePhaseApproval(w http.ResponseWriter, r *http.Request) {
 				"phase":   phase,
 			}, req.TaskID, req.PhaseID)
 		}
-
-		// Continue with the next phase if there is one
-		if execution.CurrentPhase < len(execution.Phases)-1 {
-			execution.CurrentPhase++
-			go startNextPhase(execution)
-		} else {
-			execution.Status = "completed"
-			log.Printf("üéâ [%s] All phases completed", req.TaskID)
-
-			// Broadcast task completion
-			if wsHub != nil {
-				wsHub.BroadcastMessage("task_completed", execution, req.TaskID, "")
-			}
-		}
 	} else {
 		phase.Status = "rejected"
-		execution.Status = "failed"
-		execution.Error = "Phase rejected by user: " + req.UserFeedback
-		log.Printf("‚ùå [%s] Phase %s rejected by user: %s", req.TaskID, req.PhaseID, req.UserFeedback)
+		logging.FromContext(execution.Context).With(logging.Fields{"phase_id": req.PhaseID}).Info("Phase rejected by user: %s", req.UserFeedback)
+
+		if phase.span != nil {
+			phase.span.AddEvent("phase.rejected", trace.WithAttributes(
+				attribute.String("phase.user_feedback", req.UserFeedback),
+			))
+			phase.span.SetStatus(codes.Error, "rejected by user")
+			phase.span.End()
+			phase.span = nil
+		}
 
 		// Broadcast phase rejection via WebSocket
 		if wsHub != nil {
@@ -671,6 +1265,11 @@ func handlePhaseApproval(w http.ResponseWriter, r *http.Request) {
 	// Save to database
 	saveTaskToDB(execution)
 
+	// Unblock the DAG scheduler's exec function for this phase (see
+	// sealAndGatePhase), which decides whether its dependents become
+	// eligible to run or are recorded as omitted.
+	approvalCh <- req.Approved
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -679,41 +1278,386 @@ func handlePhaseApproval(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func startNextPhase(execution *TaskExecution) {
+// runPhaseDAG drives every phase in execution.Phases to completion according
+// to their Dependencies, replacing the old strictly-linear
+// "approve phase N -> start phase N+1" progression: any phase whose
+// dependencies have all completed starts immediately, independent phases run
+// concurrently (bounded by phaseWorkerPoolSize) when the plan fans out, and a
+// failed phase's descendants are recorded "omitted" instead of executed.
+func runPhaseDAG(execution *TaskExecution) {
+	taskLog := logging.FromContext(execution.Context)
+
+	nodes := make([]dag.Node, len(execution.Phases))
+	for i, phase := range execution.Phases {
+		nodes[i] = dag.Node{ID: phase.ID, Dependencies: phase.Dependencies}
+	}
+
+	scheduler := dag.Scheduler{Concurrency: phaseWorkerPoolSize}
+	results := scheduler.Run(execution.Context, nodes, execution.Targets, func(ctx context.Context, phaseID string) error {
+		return runPhase(execution, phaseID)
+	})
+
 	tasksMutex.Lock()
-	defer tasksMutex.Unlock()
+	failed := false
+	for i := range execution.Phases {
+		phase := &execution.Phases[i]
+		res, ok := results[phase.ID]
+		if !ok {
+			continue
+		}
+		switch res.Status {
+		case dag.StatusOmitted:
+			phase.Status = "omitted"
+			phase.UserFeedback = res.Reason
+			failed = true
+		case dag.StatusFailed:
+			failed = true
+		}
+	}
+	if failed {
+		execution.Status = "failed"
+		if execution.Error == "" {
+			execution.Error = "one or more phases in the DAG failed or were omitted"
+		}
+		taskLog.Error("DAG execution finished with failures")
+	} else {
+		execution.Status = "completed"
+		taskLog.Info("All phases completed")
+	}
+	execution.UpdatedAt = time.Now()
+	if execution.TaskSpan != nil {
+		if failed {
+			execution.TaskSpan.SetStatus(codes.Error, execution.Error)
+		}
+		execution.TaskSpan.End()
+		execution.TaskSpan = nil
+	}
+	tasksMutex.Unlock()
+
+	saveTaskToDB(execution)
+	if wsHub != nil {
+		event := "task_completed"
+		if failed {
+			event = "task_failed"
+		}
+		wsHub.BroadcastMessage(event, execution, execution.ID, "")
+	}
+
+	runFinallyPhases(execution)
+}
 
-	if execution.CurrentPhase >= len(execution.Phases) {
-		log.Printf("‚ö†Ô∏è [%s] No more phases to execute", execution.ID)
+// runFinallyPhases runs execution.Finally's phases as their own DAG once
+// execution.Phases has completed, failed, or been omitted down to nothing -
+// mirroring Tekton's pipeline `finally` semantics. Unlike runPhaseDAG, a
+// finally phase is never gated behind user approval and its failure never
+// touches execution.Status/Error, which already reflect the main phase
+// list's outcome; it only surfaces in execution.FinallyResults.
+func runFinallyPhases(execution *TaskExecution) {
+	if len(execution.Finally) == 0 {
 		return
 	}
+	taskLog := logging.FromContext(execution.Context)
+	taskLog.Info("Running %d finally phase(s)", len(execution.Finally))
+
+	nodes := make([]dag.Node, len(execution.Finally))
+	for i, phase := range execution.Finally {
+		nodes[i] = dag.Node{ID: phase.ID, Dependencies: phase.Dependencies}
+	}
+
+	scheduler := dag.Scheduler{Concurrency: phaseWorkerPoolSize}
+	results := scheduler.Run(execution.Context, nodes, nil, func(ctx context.Context, phaseID string) error {
+		return runFinallyPhase(execution, phaseID)
+	})
 
-	currentPhase := &execution.Phases[execution.CurrentPhase]
-	currentPhase.Status = "running"
-	currentPhase.StartTime = &[]time.Time{time.Now()}[0]
+	tasksMutex.Lock()
+	if execution.FinallyResults == nil {
+		execution.FinallyResults = make(map[string]string)
+	}
+	for i := range execution.Finally {
+		phase := &execution.Finally[i]
+		res, ok := results[phase.ID]
+		if ok && res.Status != dag.StatusCompleted {
+			execution.FinallyResults[phase.ID+"/error"] = res.Reason
+			taskLog.With(logging.Fields{"phase_id": phase.ID}).Warn("Finally phase did not complete cleanly: %s", res.Reason)
+		}
+	}
 	execution.UpdatedAt = time.Now()
+	tasksMutex.Unlock()
 
-	log.Printf("üöÄ [%s] Starting phase %d: %s", execution.ID, execution.CurrentPhase+1, currentPhase.Name)
+	saveTaskToDB(execution)
+	if wsHub != nil {
+		wsHub.BroadcastMessage("finally_completed", execution, execution.ID, "")
+	}
+}
 
-	// Save to database
+// runFinallyPhase is the dag.ExecFunc for a single finally phase: it runs
+// the phase's experts to completion and copies their output straight into
+// execution.FinallyResults, skipping the manifest-signing and
+// approval-gating that sealAndGatePhase applies to the main phase list -
+// finally phases are meant to finish unattended.
+func runFinallyPhase(execution *TaskExecution, phaseID string) error {
+	tasksMutex.Lock()
+	var phase *ProjectPhase
+	for i := range execution.Finally {
+		if execution.Finally[i].ID == phaseID {
+			phase = &execution.Finally[i]
+			break
+		}
+	}
+	if phase == nil {
+		tasksMutex.Unlock()
+		return fmt.Errorf("finally phase %s not found in plan", phaseID)
+	}
+	phase.Status = "running"
+	phase.StartTime = &[]time.Time{time.Now()}[0]
+	phase.ctx, phase.span = tracing.StartPhaseSpan(execution.Context, execution.ID, phase.ID, phase.Name)
+	phaseLog := logging.FromContext(execution.Context).With(logging.Fields{"phase_id": phase.ID})
+	phase.ctx = logging.WithLogger(phase.ctx, phaseLog)
+	tasksMutex.Unlock()
+
+	phaseLog.Info("Starting finally phase: %s", phase.Name)
+
+	if wsHub != nil {
+		wsHub.BroadcastMessage("finally_phase_started", map[string]interface{}{
+			"taskId": execution.ID,
+			"phase":  phase,
+		}, execution.ID, phase.ID)
+	}
+
+	var wg sync.WaitGroup
+	for i := range phase.Experts {
+		wg.Add(1)
+		go func(expert *DomainExpert) {
+			defer wg.Done()
+			executeDomainExpert(phase.ctx, execution.ID, phase, expert)
+		}(&phase.Experts[i])
+	}
+	wg.Wait()
+
+	tasksMutex.Lock()
+	phase.Status = "completed"
+	phase.EndTime = &[]time.Time{time.Now()}[0]
+	if execution.FinallyResults == nil {
+		execution.FinallyResults = make(map[string]string)
+	}
+	anyFailed := false
+	for _, expert := range phase.Experts {
+		if expert.Status == "failed" {
+			anyFailed = true
+		}
+	}
+	for role, content := range phase.Results {
+		execution.FinallyResults[phase.ID+"/"+role] = content
+	}
+	if phase.span != nil {
+		if anyFailed {
+			phase.span.SetStatus(codes.Error, "one or more finally-phase experts failed")
+		}
+		phase.span.End()
+		phase.span = nil
+	}
+	tasksMutex.Unlock()
+
+	if wsHub != nil {
+		wsHub.BroadcastMessage("finally_phase_completed", map[string]interface{}{
+			"taskId": execution.ID,
+			"phase":  phase,
+		}, execution.ID, phase.ID)
+	}
+
+	if anyFailed {
+		return fmt.Errorf("one or more experts in finally phase %s failed", phase.ID)
+	}
+	return nil
+}
+
+// runPhase is the dag.ExecFunc for a single ProjectPhase: it runs that
+// phase's domain experts to completion, then hands off to
+// sealAndGatePhase. Its error return is what tells the DAG scheduler whether
+// this phase's dependents are eligible to run at all.
+func runPhase(execution *TaskExecution, phaseID string) error {
+	tasksMutex.Lock()
+	var phase *ProjectPhase
+	for i := range execution.Phases {
+		if execution.Phases[i].ID == phaseID {
+			phase = &execution.Phases[i]
+			break
+		}
+	}
+	if phase == nil {
+		tasksMutex.Unlock()
+		return fmt.Errorf("phase %s not found in plan", phaseID)
+	}
+
+	phase.Status = "running"
+	phase.StartTime = &[]time.Time{time.Now()}[0]
+	phase.approvalCh = make(chan bool, 1)
+	execution.UpdatedAt = time.Now()
+
+	// Child span for this ProjectPhase, parented off the task's root span.
+	// It stays open until the phase is approved or rejected.
+	phase.ctx, phase.span = tracing.StartPhaseSpan(execution.Context, execution.ID, phase.ID, phase.Name)
+	phaseLog := logging.FromContext(execution.Context).With(logging.Fields{"phase_id": phase.ID})
+	phase.ctx = logging.WithLogger(phase.ctx, phaseLog)
+	tasksMutex.Unlock()
+
+	phaseLog.Info("Starting phase: %s", phase.Name)
 	saveTaskToDB(execution)
 
-	// Broadcast phase start via WebSocket
 	if wsHub != nil {
 		wsHub.BroadcastMessage("phase_started", map[string]interface{}{
 			"taskId": execution.ID,
-			"phase":  currentPhase,
-		}, execution.ID, currentPhase.ID)
+			"phase":  phase,
+		}, execution.ID, phase.ID)
 	}
 
-	// Execute the domain experts in this phase
-	for i := range currentPhase.Experts {
-		go executeDomainExpert(execution.ID, currentPhase, &currentPhase.Experts[i])
+	// Execute every domain expert in this phase concurrently and wait for
+	// all of them to settle before sealing the phase's results.
+	var wg sync.WaitGroup
+	for i := range phase.Experts {
+		wg.Add(1)
+		go func(expert *DomainExpert) {
+			defer wg.Done()
+			executeDomainExpert(phase.ctx, execution.ID, phase, expert)
+		}(&phase.Experts[i])
 	}
+	wg.Wait()
+
+	return sealAndGatePhase(execution, phase)
 }
 
-func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExpert) {
-	log.Printf("üë®‚Äçüíº [%s] Starting domain expert: %s", taskID, expert.Role)
+// sealAndGatePhase seals a completed phase's expert results into a signed
+// manifest, then either auto-approves it or blocks until handlePhaseApproval
+// signals a decision on phase.approvalCh, depending on
+// execution.RequiresUserApproval. It returns an error if the phase was
+// rejected, which runPhase propagates to the DAG scheduler as a failed node.
+func sealAndGatePhase(execution *TaskExecution, phase *ProjectPhase) error {
+	taskID := execution.ID
+	phaseLog := logging.FromContext(phase.ctx)
+
+	tasksMutex.Lock()
+	phase.Status = "completed"
+	phase.EndTime = &[]time.Time{time.Now()}[0]
+	execution.UpdatedAt = time.Now()
+
+	// Seal the phase's results into a signed, content-addressed manifest
+	// before anyone can approve it: each result becomes a blob, and the
+	// manifest listing their digests is what handlePhaseApproval later
+	// re-verifies.
+	phaseArtifacts := make([]artifacts.Artifact, 0, len(phase.Results))
+	for role, content := range phase.Results {
+		art, err := artifactStore.PutArtifact(role, []byte(content))
+		if err != nil {
+			phaseLog.Error("Failed to store artifact for expert %s: %v", role, err)
+			continue
+		}
+		phaseArtifacts = append(phaseArtifacts, art)
+	}
+	if manifest, err := artifactStore.Sign(taskID, phase.ID, phaseArtifacts); err != nil {
+		phaseLog.Error("Failed to sign phase manifest: %v", err)
+	} else {
+		phase.ManifestVersion = manifest.Version
+	}
+
+	if err := saveTaskState(execution); err != nil {
+		phaseLog.Error("Failed to save phase completion: %v", err)
+	}
+	requiresApproval := execution.RequiresUserApproval
+	tasksMutex.Unlock()
+
+	if wsHub != nil {
+		wsHub.BroadcastMessage("phase_completed", map[string]interface{}{
+			"taskId": taskID,
+			"phase":  phase,
+		}, taskID, phase.ID)
+	}
+
+	// Run any pre-approval "run tasks" gates - policy engines, cost
+	// estimators, security scanners - before this phase can auto-approve or
+	// even reach a human. A failing mandatory hook rejects the phase
+	// outright; advisory and passing results just ride along on the phase
+	// for the approval UI to display.
+	if len(phase.PreApprovalHooks) > 0 && hookRunner != nil {
+		results := hookRunner.Run(execution.Context, taskID, phase.ID, phase.PreApprovalHooks, phase)
+		tasksMutex.Lock()
+		phase.HookResults = results
+		tasksMutex.Unlock()
+
+		if rejected, reason := hooks.Aggregate(phase.PreApprovalHooks, results); rejected {
+			tasksMutex.Lock()
+			phase.Status = "rejected"
+			if phase.span != nil {
+				phase.span.AddEvent("phase.rejected", trace.WithAttributes(attribute.String("phase.rejection_reason", reason)))
+				phase.span.End()
+				phase.span = nil
+			}
+			tasksMutex.Unlock()
+			phaseLog.Error("Phase '%s' auto-rejected by pre-approval hook: %s", phase.Name, reason)
+			if wsHub != nil {
+				wsHub.BroadcastMessage("phase_rejected", map[string]interface{}{
+					"taskId": taskID,
+					"phase":  phase,
+					"reason": reason,
+				}, taskID, phase.ID)
+			}
+			return fmt.Errorf("phase %s rejected by pre-approval hook: %s", phase.ID, reason)
+		}
+	}
+
+	if !requiresApproval {
+		tasksMutex.Lock()
+		phase.Approved = true
+		phase.Status = "approved"
+		if phase.span != nil {
+			phase.span.AddEvent("phase.approved", trace.WithAttributes(attribute.Bool("phase.auto_approved", true)))
+			phase.span.End()
+			phase.span = nil
+		}
+		execution.CurrentPhase++
+		tasksMutex.Unlock()
+		phaseLog.Info("Phase '%s' auto-approved.", phase.Name)
+		return nil
+	}
+
+	tasksMutex.Lock()
+	phase.Status = "awaiting_approval"
+	tasksMutex.Unlock()
+	phaseLog.Info("Phase '%s' completed. Awaiting user approval.", phase.Name)
+
+	if wsHub != nil {
+		wsHub.BroadcastMessage("phase_awaiting_approval", map[string]interface{}{
+			"taskId": taskID,
+			"phase":  phase,
+		}, taskID, phase.ID)
+	}
+
+	// Block until handlePhaseApproval signals the user's decision. The
+	// channel is created with capacity 1 in runPhase, so the sender never
+	// blocks even if this phase is never waited on again.
+	approved := <-phase.approvalCh
+	if !approved {
+		return fmt.Errorf("phase %s rejected by user", phase.ID)
+	}
+
+	tasksMutex.Lock()
+	execution.CurrentPhase++
+	tasksMutex.Unlock()
+	return nil
+}
+
+func executeDomainExpert(ctx context.Context, taskID string, phase *ProjectPhase, expert *DomainExpert) {
+	expertLog := logging.FromContext(ctx).With(logging.Fields{"expert": expert.Role})
+	ctx = logging.WithLogger(ctx, expertLog)
+	expertLog.Info("Starting domain expert")
+
+	// Grandchild span for this DomainExpert run, parented off the phase span.
+	expertCtx, span := tracing.StartExpertSpan(ctx, taskID, phase.ID, expert.Role)
+	defer func() {
+		if expert.Status == "failed" {
+			span.SetStatus(codes.Error, expert.Result)
+		}
+		span.End()
+	}()
 
 	expert.Status = "running"
 
@@ -727,14 +1671,16 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 	}
 
 	// Create agent container
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	spawnCtx, cancel := context.WithTimeout(expertCtx, 10*time.Minute)
 	defer cancel()
 
-	agentContainer, err := dockerManager.SpawnAgent(ctx)
+	spiffeID := identity.ExpertSPIFFEID(taskID, expert.Role)
+	agentContainer, expertSVID, caps, err := dockerManager.SpawnAgent(spawnCtx, spiffeID)
 	if err != nil {
+		span.AddEvent("docker.spawn.failed", trace.WithAttributes(attribute.String("error", err.Error())))
 		expert.Status = "failed"
 		expert.Result = fmt.Sprintf("Error spawning agent: %v", err)
-		log.Printf("‚ùå [%s] Failed to spawn agent for domain expert %s: %v", taskID, expert.Role, err)
+		expertLog.Error("Failed to spawn agent for domain expert: %v", err)
 
 		// Broadcast expert failure
 		if wsHub != nil {
@@ -747,16 +1693,43 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 		}
 		return
 	}
+	dockerManager.SetOwner(agentContainer.ID, fmt.Sprintf("%s/%s/expert:%s", taskID, phase.ID, expert.Role))
+	span.AddEvent("docker.spawn", trace.WithAttributes(
+		attribute.String("container.id", agentContainer.ID),
+		attribute.String("container.address", agentContainer.Address),
+	))
 
 	// Cleanup agent when done
 	defer func() {
-		log.Printf("üßπ [%s] Cleaning up agent container for %s", taskID, expert.Role)
-		if err := dockerManager.StopAgent(ctx, agentContainer.ID); err != nil {
-			log.Printf("‚ö†Ô∏è Failed to cleanup agent container: %v", err)
+		expertLog.Info("Cleaning up agent container")
+		span.AddEvent("docker.stop", trace.WithAttributes(attribute.String("container.id", agentContainer.ID)))
+		if err := dockerManager.StopAgent(spawnCtx, agentContainer.ID); err != nil {
+			expertLog.Warn("Failed to cleanup agent container: %v", err)
 		}
 	}()
 
-	log.Printf("‚úÖ [%s] Agent container spawned for %s: %s at %s", taskID, expert.Role, agentContainer.ID[:12], agentContainer.Address)
+	expertLog.Info("Agent container spawned: %s at %s", agentContainer.ID[:12], agentContainer.Address)
+
+	// Fail fast rather than dispatch a task the agent never advertised
+	// support for: a compromised or miswritten persona shouldn't get the
+	// chance to discover a missing tool mid-task.
+	if !caps.Allows(expert.RequiredCapabilities) {
+		err := fmt.Errorf("spawned agent capabilities %v do not satisfy required capabilities %v", caps.Tools, expert.RequiredCapabilities)
+		span.AddEvent("capability.mismatch", trace.WithAttributes(attribute.String("error", err.Error())))
+		expert.Status = "failed"
+		expert.Result = fmt.Sprintf("Error: %v", err)
+		expertLog.Error("%v", err)
+
+		if wsHub != nil {
+			wsHub.BroadcastMessage("expert_failed", map[string]interface{}{
+				"taskId":  taskID,
+				"phaseId": phase.ID,
+				"expert":  expert,
+				"error":   err.Error(),
+			}, taskID, phase.ID)
+		}
+		return
+	}
 
 	// Execute the expert's task with empty context data
 	contextData := make(map[string]string)
@@ -766,11 +1739,23 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 	isPhaseOne := phase.ID == "phase_1_planning" || strings.HasPrefix(phase.ID, "phase-1")
 	canDelegate := !isPhaseOne
 
-	result, err := tasks.ExecuteTaskOnAgent(agentContainer.Address, expert.Role, expert.Persona, expert.Task, contextData, canDelegate)
+	orchSVID, err := dockerManager.OrchestratorSVID()
 	if err != nil {
 		expert.Status = "failed"
 		expert.Result = fmt.Sprintf("Error: %v", err)
-		log.Printf("‚ùå [%s] Domain expert %s failed: %v", taskID, expert.Role, err)
+		return
+	}
+	peer := &identity.PeerConfig{
+		ClientSVID:     orchSVID,
+		TrustBundle:    dockerManager.TrustBundlePEM(),
+		ExpectedPeerID: spiffeID,
+	}
+
+	result, err := tasks.ExecuteTaskOnAgent(expertCtx, agentContainer.Address, expert.Role, expert.Persona, expert.Task, contextData, canDelegate, peer, expert.RequiredCapabilities, nil, nil)
+	if err != nil {
+		expert.Status = "failed"
+		expert.Result = fmt.Sprintf("Error: %v", err)
+		expertLog.Error("Domain expert failed: %v", err)
 
 		// Broadcast expert failure
 		if wsHub != nil {
@@ -788,7 +1773,7 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 	if !result.Success {
 		expert.Status = "failed"
 		expert.Result = "AGENT ERROR: " + result.ErrorMessage
-		log.Printf("‚ùå [%s] Domain expert %s reported failure: %s", taskID, expert.Role, result.ErrorMessage)
+		expertLog.Error("Domain expert reported failure: %s", result.ErrorMessage)
 
 		// Broadcast expert failure
 		if wsHub != nil {
@@ -802,6 +1787,19 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 		return
 	}
 
+	// A stale SVID, or a phase that moved on while this expert was still
+	// running, both mean the result below can no longer be trusted: the
+	// identity that produced it may have already been rotated out, or the
+	// phase it belongs to has already been approved/rejected without it.
+	tasksMutex.Lock()
+	stale := expertSVID.Expired(time.Now()) || phase.Status != "running"
+	tasksMutex.Unlock()
+	if stale {
+		expert.Status = "failed"
+		expert.Result = "AGENT ERROR: discarding result from an expired SVID or a phase that is no longer running"
+		return
+	}
+
 	expert.Status = "completed"
 	expert.Result = result.FinalContent
 
@@ -813,7 +1811,7 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 	phase.Results[expert.Role] = result.FinalContent
 	tasksMutex.Unlock()
 
-	log.Printf("‚úÖ [%s] Domain expert %s completed", taskID, expert.Role)
+	expertLog.Info("Domain expert completed")
 
 	// Broadcast expert completion via WebSocket
 	if wsHub != nil {
@@ -823,92 +1821,31 @@ func executeDomainExpert(taskID string, phase *ProjectPhase, expert *DomainExper
 			"expert":  expert,
 		}, taskID, phase.ID)
 	}
-
-	// Check if all experts in this phase are done
-	checkPhaseCompletion(taskID, phase)
 }
 
-func checkPhaseCompletion(taskID string, phase *ProjectPhase) {
-	// Check if all experts are completed
-	allCompleted := true
-	for _, expert := range phase.Experts {
-		if expert.Status != "completed" && expert.Status != "failed" {
-			allCompleted = false
-			break
-		}
-	}
-
-	if allCompleted {
-		tasksMutex.Lock()
-		defer tasksMutex.Unlock()
-
-		// Important: check the specific execution object
-		execution, exists := currentTasks[taskID]
-		if !exists {
-			return
-		}
-
-		phase.Status = "completed"
-		phase.EndTime = &[]time.Time{time.Now()}[0]
-		execution.UpdatedAt = time.Now()
-
-		// Save to database
-		if err := saveTaskState(execution); err != nil {
-			log.Printf("ERROR: Failed to save phase completion for %s: %v", taskID, err)
-		}
-
-		// Broadcast phase completion via WebSocket
-		if wsHub != nil {
-			wsHub.BroadcastMessage("phase_completed", map[string]interface{}{
-				"taskId": taskID,
-				"phase":  phase,
-			}, taskID, phase.ID)
-		}
+func executeTask(execution *TaskExecution) {
+	// Deferred until every finally phase has run (see runPhaseDAG), so a
+	// cleanup/postmortem expert can still inspect this task's containers
+	// instead of finding them already swept away.
+	defer dockerManager.CleanupAllAgents()
 
-		// Broadcast update
-		broadcastUpdate(taskID)
+	// Every log line from here through planning, phase execution, and node
+	// execution inherits task_id/run_id - and any gRPC call made with a
+	// context derived from execution.Context - by pulling its logger back
+	// out via logging.FromContext. run_id and task_id are the same value:
+	// a TaskExecution is this orchestrator's unit of "one user request", so
+	// its ID already is the run identifier every descendant log line needs
+	// to be filterable by.
+	taskLog := logging.Root.With(logging.Fields{"task_id": execution.ID, "run_id": execution.ID})
+	execution.Context = logging.WithLogger(execution.Context, taskLog)
 
-		// This is the key logic for pausing
-		if execution.RequiresUserApproval {
-			phase.Status = "awaiting_approval"
-			log.Printf("‚è≥ [%s] Phase '%s' completed. Awaiting user approval.", taskID, phase.Name)
-
-			// Broadcast awaiting approval via WebSocket
-			if wsHub != nil {
-				wsHub.BroadcastMessage("phase_awaiting_approval", map[string]interface{}{
-					"taskId": taskID,
-					"phase":  phase,
-				}, taskID, phase.ID)
-			}
-		} else {
-			// Auto-approve if user approval is not required for this task
-			phase.Approved = true
-			phase.Status = "approved"
-			log.Printf("‚úÖ [%s] Phase '%s' auto-approved.", taskID, phase.Name)
-			if execution.CurrentPhase < len(execution.Phases)-1 {
-				execution.CurrentPhase++
-				go startNextPhase(execution)
-			} else {
-				execution.Status = "completed"
-				log.Printf("üéâ [%s] All phases completed.", taskID)
-
-				// Broadcast task completion
-				if wsHub != nil {
-					wsHub.BroadcastMessage("task_completed", execution, taskID, "")
-				}
-			}
-		}
-	}
-}
-
-func executeTask(execution *TaskExecution) {
-	log.Printf("üöÄ [%s] Starting task execution: %s", execution.ID, execution.Task)
+	taskLog.Info("Starting task execution: %s", execution.Task)
 
 	tasksMutex.Lock()
 	execution.Status = "planning"
 	tasksMutex.Unlock()
 	if err := saveTaskState(execution); err != nil {
-		log.Printf("ERROR: Failed to save planning status for %s: %v", execution.ID, err)
+		taskLog.Error("Failed to save planning status: %v", err)
 	}
 
 	updateTaskStatus(execution, "planning", "", "")
@@ -917,13 +1854,13 @@ func executeTask(execution *TaskExecution) {
 	err := generatePhasedPlan(execution)
 	if err != nil {
 		updateTaskStatus(execution, "failed", "", err.Error())
-		log.Printf("‚ùå [%s] Failed to generate phased plan: %v", execution.ID, err)
+		taskLog.Error("Failed to generate phased plan: %v", err)
 		return
 	}
 
-	// Step 2: If a plan was generated successfully, start the first phase.
+	// Step 2: If a plan was generated successfully, run its phases as a DAG.
 	if len(execution.Phases) > 0 {
-		log.Printf("üé¨ [%s] Phased plan generated. Starting first phase.", execution.ID)
+		taskLog.Info("Phased plan generated. Starting DAG execution.")
 
 		// Broadcast plan generation completion
 		if wsHub != nil {
@@ -933,26 +1870,37 @@ func executeTask(execution *TaskExecution) {
 			}, execution.ID, "")
 		}
 
-		startNextPhase(execution)
+		runPhaseDAG(execution)
 	} else {
 		// Fallback for simple tasks that don't need phases.
-		log.Printf("üå≥ [%s] No phases generated, executing as a single task.", execution.ID)
+		taskLog.Info("No phases generated, executing as a single task.")
 		executeTaskWithTree(execution) // Keep the original logic as a fallback
 	}
 }
 
 // MODIFICATION 2: Create a new function to generate the phased plan.
 func generatePhasedPlan(execution *TaskExecution) error {
-	log.Printf("üìã [%s] Generating phased project plan...", execution.ID)
+	planLog := logging.FromContext(execution.Context)
+	planLog.Info("Generating phased project plan...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(execution.Context, 5*time.Minute)
 	defer cancel()
 
-	agentContainer, err := dockerManager.SpawnAgent(ctx)
+	plannerID := identity.PlannerSPIFFEID(execution.ID)
+	agentContainer, _, _, err := dockerManager.SpawnAgent(ctx, plannerID)
 	if err != nil {
 		return fmt.Errorf("failed to spawn lead agent: %v", err)
 	}
-	defer dockerManager.CleanupAllAgents()
+	dockerManager.SetOwner(agentContainer.ID, fmt.Sprintf("%s/planner", execution.ID))
+	// Stop just the planner's own container here; the broader
+	// dockerManager.CleanupAllAgents sweep is deferred until executeTask's
+	// finally phases have run, so cleanup experts can still inspect the
+	// containers from earlier phases.
+	defer func() {
+		if err := dockerManager.StopAgent(ctx, agentContainer.ID); err != nil {
+			planLog.Warn("Failed to stop planner container for %s: %v", execution.ID, err)
+		}
+	}()
 
 	// This is the updated prompt for the Lead Agent to create phases.
 	planningPrompt := fmt.Sprintf(`
@@ -962,6 +1910,8 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
 1.  **Phase 1 Restriction**: The first phase MUST NOT contain more than 10 domain experts.
 2.  **No Delegation in Phase 1**: The tasks for experts in the first phase must be self-contained. You must explicitly instruct them NOT to delegate further.
 3.  **Logical Progression**: Subsequent phases should build upon the results of the previous one (e.g., Phase 1: Planning, Phase 2: Implementation).
+4.  **Dependencies**: Every phase except the first MUST list the "id" of every phase it needs results from in its "dependencies" array. Phases that don't depend on each other (e.g. a backend phase and a frontend phase that both only need the architecture doc) will run concurrently, so only list a real dependency, not every phase that merely precedes this one in the list.
+5.  **Finally (optional)**: You MAY include a top-level "finally" array of phases, in the same shape as "phases". These always run after every phase above finishes, fails, or is cancelled - use them for postmortems, resource cleanup instructions, or a notification digest. Omit "finally" entirely if the task doesn't warrant it.
 
 **User Task:** "%s"
 
@@ -972,6 +1922,7 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
       "id": "phase_1_planning",
       "name": "Initial Design and Planning",
       "description": "Define the architecture, requirements, and user experience.",
+      "dependencies": [],
       "experts": [
         {
           "role": "Lead Architect",
@@ -985,6 +1936,7 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
       "id": "phase_2_implementation",
       "name": "Core Feature Implementation",
       "description": "Develop the key components defined in the planning phase.",
+      "dependencies": ["phase_1_planning"],
       "experts": [
         {
           "role": "Backend Developer",
@@ -994,20 +1946,47 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
         }
       ]
     }
+  ],
+  "finally": [
+    {
+      "id": "finally_postmortem",
+      "name": "Postmortem and Cleanup",
+      "description": "Summarize what was built and note any cleanup the user should perform.",
+      "dependencies": [],
+      "experts": [
+        {
+          "role": "Release Notes Writer",
+          "expertise": "Summarizing engineering work for stakeholders.",
+          "persona": "You are a technical writer. Summarize the outcome of this task, including anything that failed, into a short digest.",
+          "task": "Write a postmortem digest of this task covering what was completed, what was skipped or failed, and any manual cleanup the user should do."
+        }
+      ]
+    }
   ]
 }
 `, execution.Task)
 
 	leadPersona := "You are a JSON response generator. You ONLY output valid JSON. You never include explanations, comments, or any text outside the JSON structure."
 
-	result, err := tasks.ExecuteTaskOnAgent(agentContainer.Address, execution.ID+"-planner", leadPersona, planningPrompt, make(map[string]string), true)
+	orchSVID, err := dockerManager.OrchestratorSVID()
+	if err != nil {
+		return fmt.Errorf("failed to mint orchestrator identity: %v", err)
+	}
+	peer := &identity.PeerConfig{
+		ClientSVID:     orchSVID,
+		TrustBundle:    dockerManager.TrustBundlePEM(),
+		ExpectedPeerID: plannerID,
+	}
+
+	result, err := tasks.ExecuteTaskOnAgent(ctx, agentContainer.Address, execution.ID+"-planner", leadPersona, planningPrompt, make(map[string]string), true, peer, nil, nil, nil)
 	if err != nil || !result.Success {
 		return fmt.Errorf("lead agent failed to generate a plan. Error: %v, Agent Message: %s", err, result.GetErrorMessage())
 	}
 
 	// Unmarshal the phased plan from the agent's response
 	var planResponse struct {
-		Phases []ProjectPhase `json:"phases"`
+		Phases  []ProjectPhase `json:"phases"`
+		Finally []ProjectPhase `json:"finally,omitempty"`
 	}
 	// Sanitize the response to ensure it's valid JSON
 	jsonContent := strings.TrimSpace(result.FinalContent)
@@ -1025,9 +2004,21 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
 		return fmt.Errorf("lead agent returned a plan with no phases")
 	}
 
+	// Validate the plan's dependency graph before it ever reaches the DAG
+	// scheduler: a cycle or a dangling dependency ID would otherwise leave a
+	// phase permanently unready, deadlocking runPhaseDAG.
+	planNodes := make([]dag.Node, len(planResponse.Phases))
+	for i, phase := range planResponse.Phases {
+		planNodes[i] = dag.Node{ID: phase.ID, Dependencies: phase.Dependencies}
+	}
+	if err := dag.DetectCycle(planNodes); err != nil {
+		return fmt.Errorf("lead agent returned an invalid phase DAG: %v", err)
+	}
+
 	// Update the execution object with the new plan
 	tasksMutex.Lock()
 	execution.Phases = planResponse.Phases
+	execution.Finally = planResponse.Finally
 	execution.CurrentPhase = 0
 	execution.UpdatedAt = time.Now()
 	tasksMutex.Unlock()
@@ -1035,11 +2026,14 @@ You are a world-class AI Project Manager. Your job is to break down a complex us
 	// Save to database
 	saveTaskToDB(execution)
 
-	log.Printf("‚úÖ [%s] Successfully generated a plan with %d phases.", execution.ID, len(execution.Phases))
+	planLog.Info("Successfully generated a plan with %d phases.", len(execution.Phases))
 	return nil
 }
 func executeTaskWithTree(execution *TaskExecution) {
-	log.Printf("üå≥ [%s] Using tree-based execution", execution.ID)
+	treeLog := logging.FromContext(execution.Context)
+	treeLog.Info("Using tree-based execution")
+
+	execution.Context = withFastFail(execution.Context, execution.FastFail)
 
 	// Create task tree
 	execution.Tree = tasktree.NewTree()
@@ -1048,11 +2042,11 @@ func executeTaskWithTree(execution *TaskExecution) {
 	persona := "You are an elite AI assistant with expertise across multiple domains including technology, business, science, and creative fields. You excel at analyzing complex problems, breaking them down into manageable components, and coordinating specialized approaches when needed."
 
 	execution.RootNode = execution.Tree.AddNode("", persona, execution.Task)
-	log.Printf("üìã [%s] Created root node: %s", execution.ID, execution.RootNode.ID)
+	treeLog.Info("Created root node: %s", execution.RootNode.ID)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
-	go executeNode(execution.Context, &wg, execution.Tree, dockerManager, execution.RootNode, execution.ID)
+	go executeNode(execution.Context, &wg, execution.Tree, dockerManager, execution.RootNode, execution.ID, nil, 0)
 	wg.Wait()
 
 	// Update final status
@@ -1060,126 +2054,260 @@ func executeTaskWithTree(execution *TaskExecution) {
 	if execution.RootNode.Status == "completed" {
 		execution.Status = "completed"
 		execution.Result = execution.RootNode.Result
-		log.Printf("‚úÖ [%s] Task completed successfully", execution.ID)
+		treeLog.Info("Task completed successfully")
 	} else {
 		execution.Status = "error"
 		execution.Error = execution.RootNode.Result
-		log.Printf("‚ùå [%s] Task failed: %s", execution.ID, execution.RootNode.Result)
+		treeLog.Error("Task failed: %s", execution.RootNode.Result)
 	}
 	tasksMutex.Unlock()
 }
 
+// broadcastTreeProgress pushes a point-in-time count of nodes per status
+// (queued/running/completed/...) so the WebSocket hub can render an
+// accurate "N running / M queued" view instead of just per-node events.
+func broadcastTreeProgress(taskID string, tree *tasktree.Tree) {
+	if wsHub == nil {
+		return
+	}
+	wsHub.BroadcastMessage("tree_progress", map[string]interface{}{
+		"taskId": taskID,
+		"counts": tree.CountByStatus(),
+	}, taskID, "")
+}
+
 // executeNode is the core recursive function of the orchestrator.
-func executeNode(ctx context.Context, wg *sync.WaitGroup, tree *tasktree.Tree, dm *docker.Manager, node *tasktree.Node, taskID string) {
+// siblingSem caps how many of this node's siblings (children of the same
+// parent) run concurrently; it is nil for the root node, which has no
+// siblings. subtaskSemaphore additionally gates every node in the tree
+// against the global AGENT_INC_MAX_PARALLEL_SUBTASKS budget, so a wide
+// fan-out queues instead of spawning every container at once.
+func executeNode(ctx context.Context, wg *sync.WaitGroup, tree *tasktree.Tree, dm *docker.Manager, node *tasktree.Node, taskID string, siblingSem chan struct{}, depth int) {
 	defer wg.Done()
-	log.Printf("üöÄ [%s] Starting execution: %s", node.ID, node.Instructions[:min(100, len(node.Instructions))])
+
+	nodeLog := logging.FromContext(ctx).With(logging.Fields{"node_id": node.ID, "parent_id": node.ParentID, "depth": depth})
+	ctx = logging.WithLogger(ctx, nodeLog)
+	nodeLog.Info("Starting execution: %s", node.Instructions[:min(100, len(node.Instructions))])
+
+	tree.UpdateNodeStatus(node.ID, "queued")
+	broadcastTreeProgress(taskID, tree)
+
+	// A fast-failing sibling may cancel ctx while this node is still
+	// queued behind the semaphores below; in that case it should never
+	// spawn a container at all, just record that it was skipped.
+	if siblingSem != nil {
+		select {
+		case siblingSem <- struct{}{}:
+			defer func() { <-siblingSem }()
+		case <-ctx.Done():
+			nodeLog.Warn("Omitting sub-task: workflow fast failed")
+			tree.MarkOmitted(node.ID, "workflow fast failed")
+			broadcastTreeProgress(taskID, tree)
+			return
+		}
+	}
+	select {
+	case subtaskSemaphore <- struct{}{}:
+		defer func() { <-subtaskSemaphore }()
+	case <-ctx.Done():
+		nodeLog.Warn("Omitting sub-task: workflow fast failed")
+		tree.MarkOmitted(node.ID, "workflow fast failed")
+		broadcastTreeProgress(taskID, tree)
+		return
+	}
+	if ctx.Err() != nil {
+		nodeLog.Warn("Omitting sub-task: workflow fast failed")
+		tree.MarkOmitted(node.ID, "workflow fast failed")
+		broadcastTreeProgress(taskID, tree)
+		return
+	}
 
 	tree.UpdateNodeStatus(node.ID, "running")
+	broadcastTreeProgress(taskID, tree)
 
 	// 1. Spawn a generic agent container for this task.
-	log.Printf("üê≥ [%s] Spawning agent container...", node.ID)
-	agentContainer, err := dm.SpawnAgent(ctx)
+	nodeLog.Info("Spawning agent container...")
+	nodeSpiffeID := identity.NodeSPIFFEID(taskID, node.ID)
+	agentContainer, _, _, err := dm.SpawnAgent(ctx, nodeSpiffeID)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to spawn agent container: %v", err)
-		log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
-		tree.UpdateNodeStatus(node.ID, "failed")
+		nodeLog.Error("%s", errorMsg)
+		tree.UpdateNodeStatus(node.ID, "errored")
 		tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
 		return
 	}
+	dm.SetOwner(agentContainer.ID, fmt.Sprintf("%s/node:%s", taskID, node.ID))
+	nodeLog = nodeLog.With(logging.Fields{"container_id": agentContainer.ID[:12]})
+	ctx = logging.WithLogger(ctx, nodeLog)
 	defer func() {
-		log.Printf("üßπ [%s] Cleaning up agent container %s", node.ID, agentContainer.ID[:12])
+		nodeLog.Info("Cleaning up agent container")
 		if err := dm.StopAgent(ctx, agentContainer.ID); err != nil {
-			log.Printf("‚ö†Ô∏è [%s] Failed to cleanup container: %v", node.ID, err)
+			nodeLog.Warn("Failed to cleanup container: %v", err)
 		}
 	}()
 
-	log.Printf("‚úÖ [%s] Agent container spawned: %s at %s", node.ID, agentContainer.ID[:12], agentContainer.Address)
+	nodeLog.Info("Agent container spawned at %s", agentContainer.Address)
 
 	// 2. Get context from completed sub-tasks if this is a synthesis
 	contextData := tree.GetSubTaskResults(node.ID)
 	if len(contextData) > 0 {
-		log.Printf("üìã [%s] Using context from %d completed sub-tasks", node.ID, len(contextData))
+		nodeLog.Info("Using context from %d completed sub-tasks", len(contextData))
 	}
 
 	// 3. Execute the task on the spawned agent via gRPC.
-	log.Printf("üì° [%s] Sending task to agent...", node.ID)
-	result, err := tasks.ExecuteTaskOnAgent(agentContainer.Address, node.ID, node.Persona, node.Instructions, contextData, true)
+	nodeLog.Info("Sending task to agent...")
+	orchSVID, err := dm.OrchestratorSVID()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to mint orchestrator identity: %v", err)
+		nodeLog.Error("%s", errorMsg)
+		tree.UpdateNodeStatus(node.ID, "errored")
+		tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
+		return
+	}
+	peer := &identity.PeerConfig{
+		ClientSVID:     orchSVID,
+		TrustBundle:    dm.TrustBundlePEM(),
+		ExpectedPeerID: nodeSpiffeID,
+	}
+	result, err := tasks.ExecuteTaskOnAgent(ctx, agentContainer.Address, node.ID, node.Persona, node.Instructions, contextData, true, peer, nil, nil, func(attempt int, status, reason string) {
+		// Only record errored attempts: RecordAttempt resets the node back to
+		// "pending" so a scheduler treats it as retryable work, which would be
+		// wrong to do right before the success path below marks it completed.
+		if status == "errored" {
+			tree.RecordAttempt(node.ID, reason, status)
+		}
+	})
 	if err != nil {
 		errorMsg := fmt.Sprintf("gRPC communication failed: %v", err)
-		log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
+		nodeLog.Error("%s", errorMsg)
 
 		// Try to get container logs for debugging
 		if logs, logErr := dm.GetContainerLogs(ctx, agentContainer.ID); logErr == nil {
-			log.Printf("üîç [%s] Container logs:\n%s", node.ID, logs)
+			nodeLog.Info("Container logs:\n%s", logs)
 		} else {
-			log.Printf("‚ö†Ô∏è [%s] Could not retrieve container logs: %v", node.ID, logErr)
+			nodeLog.Warn("Could not retrieve container logs: %v", logErr)
 		}
 
-		tree.UpdateNodeStatus(node.ID, "failed")
+		tree.UpdateNodeStatus(node.ID, "errored")
 		tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
 		return
 	}
 
 	// 4. Check for agent-reported errors
 	if !result.Success {
-		log.Printf("‚ùå [%s] Agent reported failure: %s", node.ID, result.ErrorMessage)
+		nodeLog.Error("Agent reported failure: %s", result.ErrorMessage)
 		tree.UpdateNodeStatus(node.ID, "failed")
 		tree.UpdateNodeResult(node.ID, "AGENT ERROR: "+result.ErrorMessage)
 		return
 	}
 
-	log.Printf("‚úÖ [%s] Agent completed task successfully", node.ID)
+	nodeLog.Info("Agent completed task successfully")
 
 	// 5. Check if the agent decided to delegate.
 	if len(result.SubTasks) > 0 {
-		log.Printf("üîÄ [%s] Agent delegated into %d sub-tasks", node.ID, len(result.SubTasks))
+		nodeLog.Info("Agent delegated into %d sub-tasks", len(result.SubTasks))
 		tree.UpdateNodeStatus(node.ID, "delegated")
 		tree.SetRequiredSubTasks(node.ID, len(result.SubTasks))
 
 		// Log sub-task details
 		for i, subTask := range result.SubTasks {
-			log.Printf("üìù [%s] Sub-task %d: %s -> %s", node.ID, i+1,
+			nodeLog.Info("Sub-task %d: %s -> %s", i+1,
 				subTask.RequestedPersona[:min(50, len(subTask.RequestedPersona))],
 				subTask.TaskDetails[:min(100, len(subTask.TaskDetails))])
 		}
 
-		var subTaskWg sync.WaitGroup
-
+		// childSem caps how many of this node's own children run at once, on
+		// top of the tree-wide subtaskSemaphore: without it, one node
+		// delegating into 50 sub-tasks could occupy the entire global budget
+		// and starve every sibling branch in the rest of the tree.
+		childSem := make(chan struct{}, maxSubtasksPerNode)
+
+		// childCtx is what every child actually runs under. In FastFail mode
+		// it's independently cancellable so the first sibling that fails can
+		// abort the rest of the fan-out instead of everyone running to
+		// completion; outside FastFail it's just ctx, and cancelChildren is a
+		// harmless no-op cleanup.
+		fastFail := fastFailFromContext(ctx)
+		childCtx, cancelChildren := context.WithCancel(ctx)
+		defer cancelChildren()
+
+		// subPool runs this node's children through a pool.Pool instead of a
+		// bare goroutine-per-child fan-out, so their completions stream back
+		// via Results() as each one lands rather than only being observable
+		// once every sibling has finished.
+		subPool := pool.New(tree, func(jobCtx context.Context, req pool.TaskRequest) (string, bool, error) {
+			child := tree.GetNode(req.NodeID)
+			var subWg sync.WaitGroup
+			subWg.Add(1)
+			executeNode(jobCtx, &subWg, tree, dm, child, taskID, childSem, depth+1)
+			subWg.Wait()
+			return child.Result, !tree.FailsParent(child.ID), nil
+		}, maxSubtasksPerNode, 0)
+		subPool.Start(childCtx)
+
+		reqs := make([]pool.TaskRequest, len(result.SubTasks))
 		for i, subTaskReq := range result.SubTasks {
 			// Create a new node in the tree for the sub-task.
 			childNode := tree.AddNode(node.ID, subTaskReq.RequestedPersona, subTaskReq.TaskDetails)
-			log.Printf("üå± [%s] Created sub-task %d: %s", node.ID, i+1, childNode.ID)
+			tree.SetContinueOn(childNode.ID, subTaskReq.ContinueOnFailed, subTaskReq.ContinueOnErrored)
+			nodeLog.Info("Created sub-task %d: %s", i+1, childNode.ID)
+			reqs[i] = pool.TaskRequest{NodeID: childNode.ID, Persona: subTaskReq.RequestedPersona, Instructions: subTaskReq.TaskDetails}
+		}
 
-			// Recursively call executeNode for the child.
-			subTaskWg.Add(1)
-			go func(child *tasktree.Node, taskNum int) {
-				executeNode(ctx, &subTaskWg, tree, dm, child, taskID)
-			}(childNode, i+1)
+		// Order the batch by schedule.DefaultScorer before submitting it, so
+		// e.g. a try/test sub-task reaches a free worker ahead of plain
+		// siblings, instead of strictly in the Lead Agent's delegation order.
+		// Every request in the batch sits at the same depth, so the depth
+		// bonus does not change their relative order.
+		scorer := schedule.DefaultScorer{}
+		snapshot := tree.Snapshot()
+		scores := make(map[string]float64, len(reqs))
+		for _, r := range reqs {
+			if n, ok := snapshot[r.NodeID]; ok {
+				scores[r.NodeID] = scorer.Score(n, depth+1)
+			}
+		}
+		sort.SliceStable(reqs, func(i, j int) bool { return scores[reqs[i].NodeID] > scores[reqs[j].NodeID] })
 
-			// Add a longer delay between container starts to reduce resource contention
-			if i < len(result.SubTasks)-1 { // Don't delay after the last one
-				time.Sleep(2 * time.Second) // Increased from 500ms
+		batchID, err := subPool.SubmitBatch(childCtx, reqs)
+		if err != nil {
+			nodeLog.Error("Failed to submit sub-task batch: %v", err)
+			tree.UpdateNodeStatus(node.ID, "errored")
+			tree.UpdateNodeResult(node.ID, "ERROR: failed to submit sub-task batch: "+err.Error())
+			return
+		}
+
+		var fastFailOnce sync.Once
+		for range reqs {
+			res := <-subPool.Results()
+			if fastFail && tree.FailsParent(res.NodeID) {
+				fastFailOnce.Do(func() {
+					nodeLog.Warn("Sub-task %s failed; fast-failing remaining siblings", res.NodeID)
+					subPool.Cancel(batchID)
+					cancelChildren()
+				})
 			}
 		}
-		subTaskWg.Wait() // Wait for all children to finish.
 
-		// Get failed and completed sub-tasks
+		// Get failed, completed, and (FastFail-only) omitted sub-tasks.
 		failedSubTasks := tree.GetFailedSubTasks(node.ID)
 		completedSubTasks := tree.GetCompletedSubTasks(node.ID)
+		omittedSubTasks := tree.GetOmittedSubTasks(node.ID)
 
-		log.Printf("üìä [%s] Sub-task summary: %d successful, %d failed",
-			node.ID, len(completedSubTasks), len(failedSubTasks))
+		nodeLog.Info("Sub-task summary: %d successful, %d failed, %d omitted",
+			len(completedSubTasks), len(failedSubTasks), len(omittedSubTasks))
 
-		// Check if any sub-tasks failed
+		// Check if any sub-tasks failed (excluding those marked ContinueOn
+		// for their outcome, and excluding the fast-fail-omitted ones).
 		if len(failedSubTasks) > 0 {
 			errorMsg := fmt.Sprintf("Sub-task failures: %v", failedSubTasks)
-			log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
+			nodeLog.Error("%s", errorMsg)
 
 			// Log detailed error information for each failed sub-task
 			for _, failedID := range failedSubTasks {
 				failedNode := tree.GetNode(failedID)
 				if failedNode != nil {
-					log.Printf("üí• [%s] Failed sub-task %s error: %s", node.ID, failedID, failedNode.Result)
+					nodeLog.Error("Failed sub-task %s error: %s", failedID, failedNode.Result)
 				}
 			}
 
@@ -1188,64 +2316,79 @@ func executeNode(ctx context.Context, wg *sync.WaitGroup, tree *tasktree.Tree, d
 			return
 		}
 
-		// All sub-tasks are done. Now, we need to synthesize the results.
-		log.Printf("üîÑ [%s] All sub-tasks completed successfully. Starting synthesis...", node.ID)
+		// All non-omitted sub-tasks succeeded (or were tolerated via
+		// ContinueOn). Now, we need to synthesize the results.
+		nodeLog.Info("All sub-tasks completed successfully. Starting synthesis...")
 
 		// Collate results from children.
 		synthesisContext := tree.GetSubTaskResults(node.ID)
 
 		if len(synthesisContext) == 0 {
 			errorMsg := "No completed sub-tasks found for synthesis"
-			log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
-			tree.UpdateNodeStatus(node.ID, "failed")
+			nodeLog.Error("%s", errorMsg)
+			tree.UpdateNodeStatus(node.ID, "errored")
 			tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
 			return
 		}
 
-		log.Printf("üìù [%s] Synthesis context has %d sub-task results", node.ID, len(synthesisContext))
+		nodeLog.Info("Synthesis context has %d sub-task results", len(synthesisContext))
 
-		synthesisInstructions := `All your sub-agents have completed their tasks. Their reports are provided in the context data. 
+		synthesisInstructions := `All your sub-agents have completed their tasks. Their reports are provided in the context data.
 
 Your final task is to synthesize these reports into a single, cohesive document that fulfills your original objective. Create a comprehensive final report that:
 
 1. Integrates all the sub-task results logically
 2. Ensures consistency across all components
-3. Identifies any gaps or inconsistencies 
+3. Identifies any gaps or inconsistencies
 4. Provides a final, actionable deliverable
 5. Includes an executive summary
 
 Original Task: ` + node.Instructions
 
+		// Fast-fail (or a tolerated failure) can leave this node with fewer
+		// sub-task reports than it delegated into; tell the synthesizing
+		// agent which ones never ran so it doesn't present a partial result
+		// as if it were complete.
+		if len(omittedSubTasks) > 0 {
+			omittedPersonas := make([]string, 0, len(omittedSubTasks))
+			for _, omittedID := range omittedSubTasks {
+				if omittedNode := tree.GetNode(omittedID); omittedNode != nil {
+					omittedPersonas = append(omittedPersonas, omittedNode.Persona)
+				}
+			}
+			synthesisInstructions += fmt.Sprintf("\n\nNote: %d of %d sub-tasks were omitted (workflow fast failed) and are NOT reflected above: %s. Call this out explicitly instead of presenting the report as complete.", len(omittedSubTasks), len(result.SubTasks), strings.Join(omittedPersonas, ", "))
+		}
+
 		// Call the SAME agent again, but this time with the synthesis task.
-		log.Printf("üî¨ [%s] Sending synthesis task to agent...", node.ID)
-		synthesisResult, err := tasks.ExecuteTaskOnAgent(agentContainer.Address, node.ID+"-synthesis", node.Persona, synthesisInstructions, synthesisContext, true)
+		nodeLog.Info("Sending synthesis task to agent...")
+		synthesisResult, err := tasks.ExecuteTaskOnAgent(ctx, agentContainer.Address, node.ID+"-synthesis", node.Persona, synthesisInstructions, synthesisContext, true, peer, nil, nil, nil)
 		if err != nil {
 			errorMsg := fmt.Sprintf("Synthesis gRPC failed: %v", err)
-			log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
-			tree.UpdateNodeStatus(node.ID, "failed")
+			nodeLog.Error("%s", errorMsg)
+			tree.UpdateNodeStatus(node.ID, "errored")
 			tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
 			return
 		}
 
 		if !synthesisResult.Success {
 			errorMsg := fmt.Sprintf("Synthesis agent error: %s", synthesisResult.ErrorMessage)
-			log.Printf("‚ùå [%s] %s", node.ID, errorMsg)
+			nodeLog.Error("%s", errorMsg)
 			tree.UpdateNodeStatus(node.ID, "failed")
 			tree.UpdateNodeResult(node.ID, "ERROR: "+errorMsg)
 			return
 		}
 
-		log.Printf("‚úÖ [%s] Synthesis completed successfully", node.ID)
+		nodeLog.Info("Synthesis completed successfully")
 		tree.UpdateNodeResult(node.ID, synthesisResult.FinalContent)
 
 	} else {
 		// The agent executed the task directly.
-		log.Printf("‚ö° [%s] Agent executed task directly (no delegation)", node.ID)
+		nodeLog.Info("Agent executed task directly (no delegation)")
 		tree.UpdateNodeResult(node.ID, result.FinalContent)
 	}
 
 	tree.UpdateNodeStatus(node.ID, "completed")
-	log.Printf("üéâ [%s] Task completed successfully!", node.ID)
+	nodeLog.Info("Task completed successfully!")
 }
 
 // Helper function for min
@@ -0,0 +1,56 @@
+// Package redact provides an io.Writer that scrubs registered secret values
+// out of everything written through it, so an accidental future log.Printf
+// of a credential doesn't leak it.
+package redact
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// Writer wraps an underlying io.Writer, replacing every occurrence of a
+// registered secret with "[REDACTED]" before the bytes reach it.
+type Writer struct {
+	out  io.Writer
+	lock sync.RWMutex
+
+	secrets map[string]struct{}
+	ordered []string // Preserves registration order for deterministic replacement.
+}
+
+// NewWriter wraps out, initially with no secrets registered.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out, secrets: make(map[string]struct{})}
+}
+
+// Register adds a value to scrub from all future writes. Empty values are
+// ignored so an unset secret doesn't redact everything.
+func (w *Writer) Register(secret string) {
+	if secret == "" {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, seen := w.secrets[secret]; seen {
+		return
+	}
+	w.secrets[secret] = struct{}{}
+	w.ordered = append(w.ordered, secret)
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.RLock()
+	scrubbed := string(p)
+	for _, secret := range w.ordered {
+		scrubbed = strings.ReplaceAll(scrubbed, secret, "[REDACTED]")
+	}
+	w.lock.RUnlock()
+
+	if _, err := w.out.Write([]byte(scrubbed)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,387 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// secretsMountPath is where SecretMounts are injected inside the container.
+const secretsMountPath = "/run/secrets"
+
+// agentLabelKey/agentLabelValue are stamped onto every container this
+// runtime creates, so Reconcile can find survivors of a prior orchestrator
+// process with a single ContainerList label filter instead of guessing.
+const (
+	agentLabelKey   = "agentic-engineering-system"
+	agentLabelValue = "true"
+)
+
+// containerState tracks the lifecycle of a single container as reported by
+// the Docker events API.
+type containerState struct {
+	status string // "created", "running", "healthy", "exited", "oom_killed"
+	waitCh chan error
+}
+
+// dockerRuntime implements Runtime directly against the Docker Engine API.
+type dockerRuntime struct {
+	cli    *client.Client
+	states map[string]*containerState
+	lock   sync.Mutex
+}
+
+func newDockerRuntime(ctx context.Context, cfg Config) (Runtime, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &dockerRuntime{
+		cli:    cli,
+		states: make(map[string]*containerState),
+	}
+
+	go rt.watchEvents(ctx)
+
+	return rt, nil
+}
+
+// watchEvents subscribes to the Docker events API and keeps rt.states in
+// sync with the real lifecycle of every container this runtime tracks, so
+// WaitHealthy can block on a channel instead of polling.
+func (r *dockerRuntime) watchEvents(ctx context.Context) {
+	eventFilters := filters.NewArgs()
+	eventFilters.Add("type", "container")
+
+	msgs, errs := r.cli.Events(ctx, types.EventsOptions{Filters: eventFilters})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			if err != nil {
+				log.Printf("⚠️ Docker event stream error: %v", err)
+			}
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			r.handleEvent(msg)
+		}
+	}
+}
+
+func (r *dockerRuntime) handleEvent(msg events.Message) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	state, tracked := r.states[msg.ID]
+	if !tracked {
+		return // Not one of ours.
+	}
+
+	switch msg.Action {
+	case "create":
+		state.status = "created"
+	case "start":
+		state.status = "running"
+	case "die", "stop":
+		state.status = "exited"
+		r.failWaiters(state, fmt.Errorf("container %s exited", msg.ID[:12]))
+	case "oom":
+		state.status = "oom_killed"
+		r.failWaiters(state, fmt.Errorf("container %s was OOM killed", msg.ID[:12]))
+	case "health_status: healthy":
+		state.status = "healthy"
+		r.notifyWaiters(state)
+	case "health_status: unhealthy":
+		r.failWaiters(state, fmt.Errorf("container %s reported unhealthy", msg.ID[:12]))
+	}
+}
+
+func (r *dockerRuntime) notifyWaiters(state *containerState) {
+	if state.waitCh != nil {
+		close(state.waitCh)
+		state.waitCh = nil
+	}
+}
+
+func (r *dockerRuntime) failWaiters(state *containerState, err error) {
+	if state.waitCh != nil {
+		state.waitCh <- err
+		close(state.waitCh)
+		state.waitCh = nil
+	}
+}
+
+func (r *dockerRuntime) Spawn(ctx context.Context, opts SpawnOptions) (*Container, error) {
+	hostPort := opts.Port
+	if opts.RandomHostPort {
+		hostPort = "" // Let the daemon pick; we read it back after ContainerStart.
+	}
+	hostBinding := nat.PortBinding{HostIP: "", HostPort: hostPort}
+	containerPort, err := nat.NewPort("tcp", opts.Port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port: %v", err)
+	}
+	portBindings := nat.PortMap{containerPort: []nat.PortBinding{hostBinding}}
+
+	var mounts []mount.Mount
+	if len(opts.Secrets) > 0 {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeTmpfs,
+			Target: secretsMountPath,
+			TmpfsOptions: &mount.TmpfsOptions{
+				SizeBytes: 1 << 20, // 1MiB is plenty for API keys/tokens.
+				Mode:      0500,
+			},
+		})
+	}
+
+	resp, err := r.cli.ContainerCreate(ctx, &container.Config{
+		Image:        opts.Image,
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		ExposedPorts: nat.PortSet{containerPort: struct{}{}},
+		Labels:       map[string]string{agentLabelKey: agentLabelValue},
+		Healthcheck: &container.HealthConfig{
+			Test:        []string{"CMD", "grpc_health_probe", "-addr", "localhost:" + opts.Port},
+			Interval:    2 * time.Second,
+			Timeout:     2 * time.Second,
+			Retries:     15,
+			StartPeriod: 2 * time.Second,
+		},
+	}, &container.HostConfig{
+		PortBindings: portBindings,
+		AutoRemove:   false, // Disable for debugging - keep containers around to inspect
+		Mounts:       mounts,
+		Resources: container.Resources{
+			CPUShares: opts.Limits.CPUShares,
+			Memory:    opts.Limits.MemoryMB * 1024 * 1024,
+			PidsLimit: &opts.Limits.PidsLimit,
+		},
+	}, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Track the container before starting it so no event is missed.
+	r.lock.Lock()
+	r.states[resp.ID] = &containerState{status: "created"}
+	r.lock.Unlock()
+
+	if err := r.injectSecrets(ctx, resp.ID, opts.Secrets); err != nil {
+		return nil, fmt.Errorf("failed to inject secrets into %s: %w", resp.ID[:12], err)
+	}
+
+	if err := r.cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return nil, err
+	}
+
+	publishedPort := opts.Port
+	if opts.RandomHostPort {
+		publishedPort, err = r.resolveHostPort(ctx, resp.ID, containerPort)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Container{
+		ID:      resp.ID,
+		Address: "host.docker.internal:" + publishedPort, // Use Docker host reference to reach host-bound ports
+		Port:    publishedPort,
+	}, nil
+}
+
+// resolveHostPort reads back the host port Docker assigned to containerPort
+// after a RandomHostPort spawn, since HostPort: "" means the caller won't
+// know it until the daemon has picked one.
+func (r *dockerRuntime) resolveHostPort(ctx context.Context, containerID string, containerPort nat.Port) (string, error) {
+	info, err := r.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s for its assigned port: %w", containerID[:12], err)
+	}
+
+	bindings, ok := info.NetworkSettings.Ports[containerPort]
+	if !ok || len(bindings) == 0 {
+		return "", fmt.Errorf("container %s has no host port bound for %s", containerID[:12], containerPort)
+	}
+	return bindings[0].HostPort, nil
+}
+
+func (r *dockerRuntime) WaitHealthy(ctx context.Context, id string) error {
+	r.lock.Lock()
+	state, tracked := r.states[id]
+	if !tracked {
+		r.lock.Unlock()
+		return fmt.Errorf("container %s is not tracked by this runtime", id[:12])
+	}
+	if state.status == "healthy" {
+		r.lock.Unlock()
+		return nil
+	}
+	if state.status == "exited" || state.status == "oom_killed" {
+		r.lock.Unlock()
+		return fmt.Errorf("container %s already in terminal state %q", id[:12], state.status)
+	}
+	if state.waitCh == nil {
+		state.waitCh = make(chan error, 1)
+	}
+	waitCh := state.waitCh
+	r.lock.Unlock()
+
+	select {
+	case err, ok := <-waitCh:
+		if ok && err != nil {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *dockerRuntime) Stop(ctx context.Context, id string) error {
+	r.lock.Lock()
+	delete(r.states, id)
+	r.lock.Unlock()
+
+	timeout := 10
+	if err := r.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout}); err != nil {
+		return err
+	}
+	return r.cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{})
+}
+
+// Logs returns the last 50 lines of stdout+stderr, properly demultiplexed
+// from Docker's 8-byte-header framed stream instead of a single raw 4096
+// byte read (which silently truncated long logs and interleaved streams).
+func (r *dockerRuntime) Logs(ctx context.Context, id string) (string, error) {
+	out, err := r.cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       "50",
+	})
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, out); err != nil {
+		return "", fmt.Errorf("failed to demux container log stream: %w", err)
+	}
+
+	return "--- stdout ---\n" + stdout.String() + "--- stderr ---\n" + stderr.String(), nil
+}
+
+// StreamLogs follows a container's combined stdout/stderr as a channel of
+// demultiplexed, timestamped LogLines until ctx is cancelled or the
+// container exits, so long-running agents don't have their logs truncated.
+func (r *dockerRuntime) StreamLogs(ctx context.Context, id string) (<-chan LogLine, error) {
+	out, err := r.cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(chan LogLine, 64)
+
+	go func() {
+		defer close(lines)
+		defer out.Close()
+
+		stdoutW := newLogLineWriter(lines, "stdout")
+		stderrW := newLogLineWriter(lines, "stderr")
+
+		if _, err := stdcopy.StdCopy(stdoutW, stderrW, out); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Log stream for container %s ended: %v", id[:12], err)
+		}
+	}()
+
+	return lines, nil
+}
+
+// Reconcile lists every running container carrying this runtime's label,
+// re-tracks it in r.states so future events and WaitHealthy calls work, and
+// hands the caller enough to rebuild its own port bookkeeping after a
+// restart.
+func (r *dockerRuntime) Reconcile(ctx context.Context) ([]Container, error) {
+	labelFilter := filters.NewArgs()
+	labelFilter.Add("label", agentLabelKey+"="+agentLabelValue)
+
+	containers, err := r.cli.ContainerList(ctx, types.ContainerListOptions{Filters: labelFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list surviving agent containers: %w", err)
+	}
+
+	var survivors []Container
+	for _, c := range containers {
+		var port string
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 {
+				port = strconv.Itoa(int(p.PublicPort))
+				break
+			}
+		}
+		if port == "" {
+			log.Printf("⚠️ Skipping surviving container %s: no published host port found", c.ID[:12])
+			continue
+		}
+
+		r.lock.Lock()
+		if _, tracked := r.states[c.ID]; !tracked {
+			r.states[c.ID] = &containerState{status: "running"}
+		}
+		r.lock.Unlock()
+
+		survivors = append(survivors, Container{
+			ID:      c.ID,
+			Address: "host.docker.internal:" + port,
+			Port:    port,
+		})
+	}
+
+	return survivors, nil
+}
+
+func (r *dockerRuntime) Cleanup(ctx context.Context) error {
+	r.lock.Lock()
+	ids := make([]string, 0, len(r.states))
+	for id := range r.states {
+		ids = append(ids, id)
+	}
+	r.states = make(map[string]*containerState)
+	r.lock.Unlock()
+
+	for _, id := range ids {
+		timeout := 5
+		_ = r.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout})
+		_ = r.cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{})
+		log.Printf("Cleaned up container %s", id[:12])
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// logLineWriter is an io.Writer adapter that splits a raw byte stream into
+// newline-delimited LogLine values, parsing the leading RFC3339Nano
+// timestamp that Docker prepends when Timestamps is requested.
+type logLineWriter struct {
+	out    chan<- LogLine
+	stream string
+	buf    bytes.Buffer
+}
+
+func newLogLineWriter(out chan<- LogLine, stream string) *logLineWriter {
+	return &logLineWriter{out: out, stream: stream}
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break // Incomplete line: leave it buffered for the next Write.
+		}
+		line := strings.TrimSuffix(string(w.buf.Next(idx+1)), "\n")
+		w.out <- parseLogLine(w.stream, line)
+	}
+
+	return len(p), nil
+}
+
+func parseLogLine(stream, line string) LogLine {
+	ts := time.Now()
+	msg := line
+
+	if parts := strings.SplitN(line, " ", 2); len(parts) == 2 {
+		if parsed, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+			ts = parsed
+			msg = parts[1]
+		}
+	}
+
+	return LogLine{Stream: stream, Timestamp: ts, Message: msg}
+}
@@ -0,0 +1,130 @@
+// Package runtime abstracts the container lifecycle operations the
+// orchestrator needs to run an agent sandbox, so that backend (Docker,
+// containerd+runc, or a hardened containerd+gVisor/Kata handler) can be
+// swapped via configuration instead of call-site changes.
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backend selects which underlying sandbox technology spawns agent
+// containers.
+type Backend string
+
+const (
+	BackendDocker     Backend = "docker"
+	BackendContainerd Backend = "containerd"
+)
+
+// OCI runtime handlers available to the containerd backend.
+const (
+	HandlerRunc  = "runc"  // Default, no extra isolation.
+	HandlerRunsc = "runsc" // gVisor, for sandboxing untrusted LLM-generated code.
+	HandlerKata  = "kata"  // Kata Containers, VM-backed isolation.
+)
+
+// ResourceLimits caps what a single agent container may consume. These are
+// runtime-neutral so callers don't need to know which backend is active.
+type ResourceLimits struct {
+	CPUShares int64
+	MemoryMB  int64
+	PidsLimit int64
+}
+
+// SecretMount is a single secret to inject into a sandbox at
+// /run/secrets/<Name>, instead of container.Config.Env where it would leak
+// into `docker inspect` and any log aggregator that captures env vars.
+type SecretMount struct {
+	Name  string
+	Value string
+}
+
+// SpawnOptions describes the container an agent should run in.
+type SpawnOptions struct {
+	Image   string
+	Cmd     []string
+	Env     []string
+	Port    string // Container port the agent's gRPC server listens on.
+	Limits  ResourceLimits
+	Secrets []SecretMount
+
+	// RandomHostPort lets the backend pick an arbitrary free host port
+	// instead of binding the host to Port directly, for backends (and
+	// callers) that would rather avoid pre-allocating a matching host port
+	// themselves. The actual assigned port is read back onto the returned
+	// Container.
+	RandomHostPort bool
+}
+
+// Container is a handle to a running agent sandbox.
+type Container struct {
+	ID      string
+	Address string // e.g., "host.docker.internal:50060"
+	Port    string
+}
+
+// LogLine is a single demultiplexed, timestamped line of container output.
+type LogLine struct {
+	Stream    string // "stdout" or "stderr"
+	Timestamp time.Time
+	Message   string
+}
+
+// Runtime is implemented by every supported sandbox backend.
+type Runtime interface {
+	// Spawn creates and starts a new agent sandbox.
+	Spawn(ctx context.Context, opts SpawnOptions) (*Container, error)
+	// WaitHealthy blocks until the sandbox reports healthy, or returns an
+	// error if it exits/crashes first or ctx is cancelled.
+	WaitHealthy(ctx context.Context, id string) error
+	// Stop tears down a single sandbox.
+	Stop(ctx context.Context, id string) error
+	// Logs returns recent combined stdout/stderr output for debugging.
+	Logs(ctx context.Context, id string) (string, error)
+	// StreamLogs follows combined stdout/stderr until ctx is cancelled or
+	// the sandbox exits.
+	StreamLogs(ctx context.Context, id string) (<-chan LogLine, error)
+	// Reconcile returns sandboxes from a prior process generation that are
+	// still alive, identified by a label this runtime attaches to every
+	// sandbox it creates, so a restarted caller can rediscover survivors
+	// instead of losing track of them.
+	Reconcile(ctx context.Context) ([]Container, error)
+	// Cleanup tears down every sandbox this Runtime has spawned.
+	Cleanup(ctx context.Context) error
+}
+
+// Config selects a backend and its runtime-specific options.
+type Config struct {
+	Backend Backend
+
+	// Containerd-only options.
+	ContainerdAddress   string
+	ContainerdNamespace string
+	OCIRuntimeHandler   string // One of HandlerRunc/HandlerRunsc/HandlerKata.
+}
+
+// DefaultConfig returns the plain Docker backend with no extra sandboxing,
+// matching the orchestrator's historical behavior.
+func DefaultConfig() Config {
+	return Config{
+		Backend:             BackendDocker,
+		ContainerdAddress:   "/run/containerd/containerd.sock",
+		ContainerdNamespace: "agentic-engineering-system",
+		OCIRuntimeHandler:   HandlerRunc,
+	}
+}
+
+// New constructs the Runtime selected by cfg.Backend.
+func New(ctx context.Context, cfg Config) (Runtime, error) {
+	switch cfg.Backend {
+	case BackendContainerd:
+		return newContainerdRuntime(ctx, cfg)
+	case BackendDocker, "":
+		return newDockerRuntime(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("runtime: unknown backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// injectSecrets writes each SecretMount into the container's tmpfs-backed
+// /run/secrets mount via CopyToContainer, an in-memory tar stream that
+// never touches the host filesystem or the image's layers.
+func (r *dockerRuntime) injectSecrets(ctx context.Context, containerID string, secrets []SecretMount) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, s := range secrets {
+		if s.Value == "" {
+			continue // e.g. a swarm-native secret that's already mounted by the daemon.
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: s.Name, Mode: 0400, Size: int64(len(s.Value))}); err != nil {
+			return fmt.Errorf("failed to write header for secret %q: %w", s.Name, err)
+		}
+		if _, err := tw.Write([]byte(s.Value)); err != nil {
+			return fmt.Errorf("failed to write secret %q: %w", s.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return r.cli.CopyToContainer(ctx, containerID, secretsMountPath, &buf, types.CopyToContainerOptions{})
+}
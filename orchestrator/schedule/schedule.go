@@ -0,0 +1,71 @@
+// Package schedule implements priority scoring on top of tasktree.Tree,
+// modeled on the Kubernetes scheduler's scoring step: a pluggable Scorer
+// ranks a batch of ready nodes so a caller can dispatch the highest-scoring
+// ones first. This gives a long multi-phase run predictable latency for
+// user-driven (force-run) or retry-sensitive work instead of dispatching
+// strictly in creation (FIFO) order.
+package schedule
+
+import (
+	"strings"
+
+	"agentic-engineering-system/tasktree"
+)
+
+// Scorer assigns a priority score to a single ready node, so a caller can
+// dispatch its highest-scoring candidates first. depth is how many
+// ancestors node has (0 for a root node).
+type Scorer interface {
+	Score(node tasktree.NodeSnapshot, depth int) float64
+}
+
+// Bonuses/penalties DefaultScorer combines into a node's final score.
+const (
+	// ForceRunBonus is added for a user-flagged (ForceRun) node.
+	ForceRunBonus = 100.0
+	// CategoryBonus is added for a node whose Persona looks like a
+	// try/test role, so verification work gets dispatched promptly once
+	// its dependencies are ready.
+	CategoryBonus = 20.0
+	// RetryPenalty is multiplied into a node's score once per prior
+	// Attempts, so freshly-queued work outranks retries of the same depth
+	// and category.
+	RetryPenalty = 0.75
+	// DepthBonusPerLevel is added per ancestor a node has, so work needed
+	// to unblock a shallower parent is prioritized over starting fresh
+	// branches closer to the root.
+	DepthBonusPerLevel = 5.0
+	// MishapBonus is added when the node's last attempt crashed
+	// ("errored") rather than ending in an ordinary business failure
+	// ("failed"), since a mishap is more likely to succeed on retry.
+	MishapBonus = 15.0
+)
+
+// DefaultScorer implements the repo's standard prioritization described by
+// the Bonuses/penalties constants above.
+type DefaultScorer struct{}
+
+func (DefaultScorer) Score(node tasktree.NodeSnapshot, depth int) float64 {
+	score := node.Priority
+
+	if node.ForceRun {
+		score += ForceRunBonus
+	}
+	if isTryTestCategory(node.Persona) {
+		score += CategoryBonus
+	}
+	score += float64(depth) * DepthBonusPerLevel
+	if node.LastStatus == "errored" {
+		score += MishapBonus
+	}
+	for i := 0; i < node.Attempts; i++ {
+		score *= RetryPenalty
+	}
+
+	return score
+}
+
+func isTryTestCategory(persona string) bool {
+	p := strings.ToLower(persona)
+	return strings.Contains(p, "test") || strings.Contains(p, "qa") || strings.Contains(p, "try") || strings.Contains(p, "verif")
+}
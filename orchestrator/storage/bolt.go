@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltStore implements Interface on top of a single bbolt database, for
+// single-node orchestrator deployments. ResourceVersion is the bucket's own
+// monotonic sequence counter, so every write to any key advances it -
+// matching etcd's single global revision rather than a per-key counter.
+// Watch has no durable history: a watcher only receives events emitted
+// after it subscribes, regardless of fromResourceVersion, since bbolt keeps
+// no change log to replay from. A watcher whose channel buffer is full
+// misses the events published while it is full, rather than blocking
+// writers.
+type BoltStore struct {
+	db     *bbolt.DB
+	bucket []byte
+
+	mu       sync.Mutex
+	watchers []*boltWatcher
+}
+
+type boltWatcher struct {
+	key string // exact key, or a "prefix/" the watcher matches against
+	ch  chan Event
+}
+
+// NewBoltStore opens (creating if necessary) bucketName in db and returns a
+// BoltStore backed by it.
+func NewBoltStore(db *bbolt.DB, bucketName string) (*BoltStore, error) {
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("storage: failed to create bucket %q: %w", bucketName, err)
+	}
+	return &BoltStore{db: db, bucket: []byte(bucketName)}, nil
+}
+
+func encodeEntry(value []byte, resourceVersion int64) []byte {
+	buf := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(buf[:8], uint64(resourceVersion))
+	copy(buf[8:], value)
+	return buf
+}
+
+func decodeEntry(raw []byte) ([]byte, int64) {
+	return raw[8:], int64(binary.BigEndian.Uint64(raw[:8]))
+}
+
+func (s *BoltStore) Get(ctx context.Context, key string) ([]byte, int64, error) {
+	var value []byte
+	var rv int64
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(s.bucket).Get([]byte(key))
+		if raw == nil {
+			return ErrNotFound
+		}
+		v, r := decodeEntry(raw)
+		value = append([]byte(nil), v...)
+		rv = r
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, rv, nil
+}
+
+func (s *BoltStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var out []KeyValue
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		prefixBytes := []byte(prefix)
+		for k, raw := c.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, raw = c.Next() {
+			v, rv := decodeEntry(raw)
+			out = append(out, KeyValue{Key: string(k), Value: append([]byte(nil), v...), ResourceVersion: rv})
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (s *BoltStore) Create(ctx context.Context, key string, value []byte) (int64, error) {
+	var rv int64
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b.Get([]byte(key)) != nil {
+			return fmt.Errorf("storage: key %q already exists", key)
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		rv = int64(seq)
+		return b.Put([]byte(key), encodeEntry(value, rv))
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.notify(Event{Type: EventAdded, Key: key, Value: value, ResourceVersion: rv})
+	return rv, nil
+}
+
+// GuaranteedUpdate retries a bounded number of times rather than forever, so
+// a persistently hot key fails loudly instead of spinning a goroutine
+// indefinitely.
+const maxGuaranteedUpdateAttempts = 10
+
+func (s *BoltStore) GuaranteedUpdate(ctx context.Context, key string, tryUpdate TryUpdateFunc) ([]byte, int64, error) {
+	for attempt := 0; attempt < maxGuaranteedUpdateAttempts; attempt++ {
+		var current []byte
+		var baseRV int64
+		existed := true
+		if err := s.db.View(func(tx *bbolt.Tx) error {
+			raw := tx.Bucket(s.bucket).Get([]byte(key))
+			if raw == nil {
+				existed = false
+				return nil
+			}
+			v, r := decodeEntry(raw)
+			current = append([]byte(nil), v...)
+			baseRV = r
+			return nil
+		}); err != nil {
+			return nil, 0, err
+		}
+
+		next, err := tryUpdate(current)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var newRV int64
+		conflict := false
+		if err := s.db.Update(func(tx *bbolt.Tx) error {
+			b := tx.Bucket(s.bucket)
+			raw := b.Get([]byte(key))
+			observedExists := raw != nil
+			var observedRV int64
+			if observedExists {
+				_, observedRV = decodeEntry(raw)
+			}
+			if observedExists != existed || observedRV != baseRV {
+				conflict = true
+				return nil
+			}
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			newRV = int64(seq)
+			return b.Put([]byte(key), encodeEntry(next, newRV))
+		}); err != nil {
+			return nil, 0, err
+		}
+		if conflict {
+			continue
+		}
+
+		eventType := EventModified
+		if !existed {
+			eventType = EventAdded
+		}
+		s.notify(Event{Type: eventType, Key: key, Value: next, ResourceVersion: newRV})
+		return next, newRV, nil
+	}
+	return nil, 0, fmt.Errorf("storage: GuaranteedUpdate on %q did not converge after %d attempts", key, maxGuaranteedUpdateAttempts)
+}
+
+func (s *BoltStore) Delete(ctx context.Context, key string) error {
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(key))
+	}); err != nil {
+		return err
+	}
+	s.notify(Event{Type: EventDeleted, Key: key})
+	return nil
+}
+
+func (s *BoltStore) Watch(ctx context.Context, key string, fromResourceVersion int64) (<-chan Event, error) {
+	w := &boltWatcher{key: key, ch: make(chan Event, 16)}
+
+	s.mu.Lock()
+	s.watchers = append(s.watchers, w)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, existing := range s.watchers {
+			if existing == w {
+				s.watchers = append(s.watchers[:i], s.watchers[i+1:]...)
+				break
+			}
+		}
+		close(w.ch)
+	}()
+
+	return w.ch, nil
+}
+
+func (s *BoltStore) notify(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range s.watchers {
+		if strings.HasSuffix(w.key, "/") {
+			if !strings.HasPrefix(ev.Key, w.key) {
+				continue
+			}
+		} else if w.key != ev.Key {
+			continue
+		}
+		select {
+		case w.ch <- ev:
+		default:
+			// Slow watcher; drop rather than block writers.
+		}
+	}
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,88 @@
+// Package storage implements a Kubernetes/etcd3-style resource-versioned
+// key/value abstraction for orchestrator state: every stored value carries
+// a monotonically increasing ResourceVersion, GuaranteedUpdate does an
+// optimistic-concurrency read-modify-write loop instead of a blind
+// overwrite, and Watch streams every Create/GuaranteedUpdate/Delete as an
+// ordered event so callers don't have to poll. Two backends implement
+// Interface: a bbolt-backed store for single-node deployments and an etcd
+// v3 client for HA deployments where several orchestrator replicas share
+// state.
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by Get when no value exists for the key.
+// GuaranteedUpdate does not return it for a missing key; it calls
+// tryUpdate with a nil current value instead.
+var ErrNotFound = errors.New("storage: key not found")
+
+// EventType describes what happened to a key in a Watch stream.
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+)
+
+// Event is one change to a watched key or prefix.
+type Event struct {
+	Type            EventType
+	Key             string
+	Value           []byte // nil for EventDeleted
+	ResourceVersion int64
+}
+
+// KeyValue is one entry returned by List.
+type KeyValue struct {
+	Key             string
+	Value           []byte
+	ResourceVersion int64
+}
+
+// TryUpdateFunc computes the next value to persist from the current one.
+// current is nil if the key doesn't exist yet. Returning an error aborts
+// the update without retrying.
+type TryUpdateFunc func(current []byte) ([]byte, error)
+
+// Interface is the storage abstraction every backend implements. Keys are
+// flat strings (e.g. "tasks/<id>"); values are caller-supplied byte slices
+// that this package never interprets, matching etcd's own model.
+type Interface interface {
+	// Get returns the current value and ResourceVersion for key, or
+	// ErrNotFound.
+	Get(ctx context.Context, key string) (value []byte, resourceVersion int64, err error)
+
+	// List returns every key currently stored under prefix, in backend-
+	// defined order.
+	List(ctx context.Context, prefix string) ([]KeyValue, error)
+
+	// Create stores value under key only if it doesn't already exist.
+	Create(ctx context.Context, key string, value []byte) (resourceVersion int64, err error)
+
+	// GuaranteedUpdate loads the current value for key, applies tryUpdate,
+	// and writes the result back guarded by a compare-and-swap on
+	// ResourceVersion, retrying the whole read-modify-write if another
+	// writer won the race. It returns the value and ResourceVersion that
+	// were actually persisted. If key doesn't exist, tryUpdate is called
+	// once with a nil current value and the result is created.
+	GuaranteedUpdate(ctx context.Context, key string, tryUpdate TryUpdateFunc) (value []byte, resourceVersion int64, err error)
+
+	// Delete removes key. It is not an error to delete a key that doesn't
+	// exist.
+	Delete(ctx context.Context, key string) error
+
+	// Watch streams every change to key from fromResourceVersion (exclusive)
+	// onward; a fromResourceVersion of 0 means "start now". A key ending in
+	// "/" watches every key under that prefix instead of a single key. The
+	// returned channel is closed when ctx is cancelled. BoltStore drops
+	// events for a watcher whose buffer is full rather than blocking
+	// writers; see its doc comment.
+	Watch(ctx context.Context, key string, fromResourceVersion int64) (<-chan Event, error)
+
+	// Close releases backend resources (connections, file handles).
+	Close() error
+}
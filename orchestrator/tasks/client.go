@@ -2,57 +2,198 @@ package tasks
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"math/rand"
 	"time"
 
+	"agentic-engineering-system/identity"
+	"agentic-engineering-system/logging"
 	pb "agentic-engineering-system/proto"
+	"agentic-engineering-system/tracing"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
-// ExecuteTaskOnAgent sends a task to an agent via gRPC and returns the result
-func ExecuteTaskOnAgent(address, taskID, persona, instructions string, contextData map[string]string, canDelegate bool) (*pb.TaskResult, error) {
-	// Retry logic for connection issues
-	maxRetries := 3
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		log.Printf("🔄 [%s] Attempt %d/%d: Connecting to agent at %s", taskID, attempt, maxRetries, address)
+// RetryableError is implemented by an error that knows whether retrying the
+// call that produced it could succeed. ExecuteTaskOnAgent checks for it via
+// errors.As before falling back to classifyRetryable's gRPC status
+// classification, so a caller with a more specific notion of "retryable"
+// than a bare status code can still drive the same retry loop.
+type RetryableError interface {
+	error
+	IsRetryable() bool
+}
+
+// RetryPolicy controls ExecuteTaskOnAgent's retry schedule. Delay for the
+// n-th retry (n starting at 1) is min(MaxBackoff,
+// InitialBackoff*Multiplier^(n-1)), scaled by 1+rand.Float64()*JitterFraction
+// so concurrent sub-tasks retrying after a shared failure (e.g. a
+// warm-pool container reset) don't all reconnect in lockstep.
+type RetryPolicy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	Multiplier        float64
+	JitterFraction    float64
+	PerAttemptTimeout time.Duration
+}
+
+// DefaultRetryPolicy is ExecuteTaskOnAgent's policy when the caller doesn't
+// supply one: 3 attempts, backoff doubling from 1s up to a 16s cap, up to
+// 50% jitter, 30s per attempt. The attempt count and per-attempt timeout
+// are the ones this package used before RetryPolicy existed; the backoff
+// replaces its linear attempt*2s delay.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    1 * time.Second,
+		MaxBackoff:        16 * time.Second,
+		Multiplier:        2,
+		JitterFraction:    0.5,
+		PerAttemptTimeout: 30 * time.Second,
+	}
+}
+
+// delay returns how long to wait before the n-th retry (n >= 1).
+func (p RetryPolicy) delay(n int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(n-1))
+	if max := float64(p.MaxBackoff); backoff > max {
+		backoff = max
+	}
+	return time.Duration(backoff * (1 + rand.Float64()*p.JitterFraction))
+}
+
+// classifyRetryable reports whether err looks transient enough to retry. A
+// RetryableError is deferred to directly; otherwise a gRPC status code of
+// Unavailable, DeadlineExceeded, ResourceExhausted, or Aborted is retried,
+// while InvalidArgument, PermissionDenied, NotFound, Unauthenticated, and
+// FailedPrecondition fail fast since retrying them can't change the
+// outcome. An error status.FromError can't classify (e.g. a dial failure
+// before any RPC status comes back) is retried, matching this package's
+// previous retry-everything behavior for connection setup issues.
+func classifyRetryable(err error) bool {
+	var re RetryableError
+	if errors.As(err, &re) {
+		return re.IsRetryable()
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch st.Code() {
+	case codes.InvalidArgument, codes.PermissionDenied, codes.NotFound, codes.Unauthenticated, codes.FailedPrecondition:
+		return false
+	default:
+		return true
+	}
+}
+
+// AttemptObserver is notified of each ExecuteTaskOnAgent retry's outcome, in
+// order, as it settles - status is "succeeded" or "errored" (every failure
+// this package retries is an infra/gRPC problem, same as tasktree's
+// "errored" status elsewhere), reason is the error (empty on success). A
+// caller executing a tasktree-backed task wires this to tree.RecordAttempt
+// so the scheduler's retry-penalty scorer and the reconciler see the full
+// attempt history, not just a count.
+type AttemptObserver func(attempt int, status, reason string)
+
+// ExecuteTaskOnAgent sends a task to an agent via gRPC and returns the
+// result, retrying per policy (DefaultRetryPolicy if nil) on errors
+// classifyRetryable judges transient. ctx should carry the caller's active
+// span (e.g. a phase or expert span) so the outbound gRPC call propagates a
+// W3C traceparent header into the agent container instead of starting a
+// disconnected trace there, and also bounds the whole retry loop - a
+// cancelled ctx aborts immediately instead of sleeping out the remaining
+// backoff. peer is nil when mTLS isn't configured for this call (falls back
+// to an insecure dial); otherwise the connection is authenticated and the
+// agent's SVID is pinned to peer.ExpectedPeerID before any RPC is sent.
+// allowedTools is forwarded to the agent so it can refuse to use a tool
+// outside this specific task's allowlist even if its advertised
+// capabilities are broader; a nil/empty allowedTools imposes no additional
+// restriction. onAttempt may be nil.
+func ExecuteTaskOnAgent(ctx context.Context, address, taskID, persona, instructions string, contextData map[string]string, canDelegate bool, peer *identity.PeerConfig, allowedTools []string, policy *RetryPolicy, onAttempt AttemptObserver) (*pb.TaskResult, error) {
+	p := DefaultRetryPolicy()
+	if policy != nil {
+		p = *policy
+	}
+
+	callLog := logging.FromContext(ctx).With(logging.Fields{"task_id": taskID, "persona": persona, "agent_addr": address})
+
+	var lastErr error
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		attemptCtx, span := tracing.StartAgentCallSpan(ctx, taskID, attempt)
+		attemptLog := callLog.With(logging.Fields{"attempt": attempt})
+		attemptLog.Info("Connecting to agent (attempt %d/%d)", attempt, p.MaxAttempts)
 
-		result, err := attemptTaskExecution(address, taskID, persona, instructions, contextData, canDelegate)
+		result, err := attemptTaskExecution(attemptCtx, address, taskID, persona, instructions, contextData, canDelegate, peer, allowedTools, p.PerAttemptTimeout)
+		span.End()
 		if err == nil {
+			if onAttempt != nil {
+				onAttempt(attempt, "succeeded", "")
+			}
 			return result, nil
 		}
+		lastErr = err
 
-		log.Printf("⚠️ [%s] Attempt %d failed: %v", taskID, attempt, err)
+		attemptLog.Warn("Attempt failed: %v", err)
+		if onAttempt != nil {
+			onAttempt(attempt, "errored", err.Error())
+		}
+
+		if !classifyRetryable(err) {
+			attemptLog.Error("Non-retryable error, giving up early: %v", err)
+			return nil, fmt.Errorf("non-retryable: %w", err)
+		}
 
-		if attempt < maxRetries {
-			waitTime := time.Duration(attempt) * 2 * time.Second
-			log.Printf("⏳ [%s] Waiting %v before retry...", taskID, waitTime)
-			time.Sleep(waitTime)
+		if attempt < p.MaxAttempts {
+			waitTime := p.delay(attempt)
+			attemptLog.Info("Waiting %v before retry", waitTime)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
 	}
 
-	return nil, fmt.Errorf("failed after %d attempts", maxRetries)
+	return nil, fmt.Errorf("failed after %d attempts: %w", p.MaxAttempts, lastErr)
 }
 
-func attemptTaskExecution(address, taskID, persona, instructions string, contextData map[string]string, canDelegate bool) (*pb.TaskResult, error) {
+func attemptTaskExecution(ctx context.Context, address, taskID, persona, instructions string, contextData map[string]string, canDelegate bool, peer *identity.PeerConfig, allowedTools []string, perAttemptTimeout time.Duration) (*pb.TaskResult, error) {
+	callLog := logging.FromContext(ctx).With(logging.Fields{"task_id": taskID, "persona": persona, "agent_addr": address})
+
 	// Connect to the agent using the exact pattern that works in minimal test
-	log.Printf("🔌 [%s] Establishing gRPC connection to %s", taskID, address)
+	callLog.Info("Establishing gRPC connection")
 
-	// Use the exact same pattern as the working minimal test
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	dialCtx, cancel := context.WithTimeout(ctx, perAttemptTimeout)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	transportCreds := insecure.NewCredentials()
+	if peer != nil {
+		tlsConfig, err := peer.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build mTLS config for %s: %v", address, err)
+		}
+		transportCreds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.DialContext(dialCtx, address,
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gRPC client for %s: %v", address, err)
 	}
 	defer conn.Close()
 
-	log.Printf("✅ [%s] gRPC client created", taskID)
+	callLog.Info("gRPC client created")
 
 	// Create gRPC client
 	client := pb.NewGenericAgentClient(conn)
@@ -68,15 +209,37 @@ func attemptTaskExecution(address, taskID, persona, instructions string, context
 		TaskInstructions: instructions,
 		ContextData:      contextData,
 		CanDelegate:      canDelegate,
+		AllowedTools:     allowedTools,
 	}
 
 	// Execute the task using the same context as connection
-	log.Printf("📤 [%s] Sending task to agent...", taskID)
-	result, err := client.ExecuteTask(ctx, request)
+	callLog.Info("Sending task to agent...")
+	result, err := client.ExecuteTask(dialCtx, request)
 	if err != nil {
-		return nil, fmt.Errorf("gRPC call failed: %v", err)
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
 	}
 
-	log.Printf("✅ [%s] Task completed successfully. Success: %v", taskID, result.Success)
+	callLog.Info("Task completed successfully. Success: %v", result.Success)
 	return result, nil
 }
+
+// ResetAgent asks a warm agent to clear its conversation/context state so it
+// can be handed back to a pool and reused for an unrelated task, instead of
+// being torn down and recreated.
+func ResetAgent(address string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC client for %s: %v", address, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewGenericAgentClient(conn)
+	if _, err := client.Reset(ctx, &pb.ResetRequest{}); err != nil {
+		return fmt.Errorf("reset RPC failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+// Package pool implements a fixed-size worker pool for fanning a
+// tasktree.Node's sub-tasks out to agents, modeled on a Kubernetes
+// workqueue: callers enqueue a batch of TaskRequests and get a BatchID back
+// immediately, then drain Results() as completions stream in instead of
+// blocking until every sibling finishes. This lets a caller start acting on
+// the first sub-task to land (logging, partial synthesis, fast-fail
+// cancellation) without waiting on the slowest one in the batch.
+//
+// The pool deliberately stays thin on policy: it runs whatever RunFunc the
+// caller supplies (container spawn + gRPC dispatch lives in the
+// orchestrator, not here) and only owns scheduling concerns - a fixed worker
+// count, a per-agent-address concurrency cap so one slow/misbehaving agent
+// can't starve the rest of a batch, and tasktree bookkeeping around each
+// job.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"agentic-engineering-system/tasktree"
+)
+
+// TaskRequest is one unit of fan-out work: a tasktree.Node to run on Agent.
+type TaskRequest struct {
+	NodeID       string
+	Agent        string
+	Persona      string
+	Instructions string
+	ContextData  map[string]string
+	CanDelegate  bool
+	AllowedTools []string
+}
+
+// TaskResult is what a worker reports once a TaskRequest settles.
+type TaskResult struct {
+	BatchID BatchID
+	NodeID  string
+	Content string
+	Success bool
+	Err     error
+}
+
+// BatchID identifies one SubmitBatch call.
+type BatchID string
+
+// RunFunc actually executes a TaskRequest (spawning a container, dialing the
+// agent, awaiting its response) and reports the outcome. The pool calls it
+// from a worker goroutine and treats a non-nil err the same as Success ==
+// false for BatchStatus accounting, but keeps them distinct on TaskResult so
+// the caller can tell an infra error (Err set) from an agent-reported
+// business failure (Success == false, Err nil).
+type RunFunc func(ctx context.Context, req TaskRequest) (content string, success bool, err error)
+
+// BatchCounts summarizes how many of a batch's TaskRequests are in each
+// state.
+type BatchCounts struct {
+	Pending   int
+	Running   int
+	Completed int
+	Failed    int
+}
+
+type job struct {
+	batch BatchID
+	req   TaskRequest
+	ctx   context.Context // the batch's own cancellable context, not the pool's
+}
+
+type batchState struct {
+	total     int
+	pending   int
+	running   int
+	completed int
+	failed    int
+	cancel    context.CancelFunc
+}
+
+// Pool runs TaskRequests across a fixed set of worker goroutines, capping
+// per-agent-address concurrency on top of the pool-wide worker count so a
+// single slow agent can't saturate every worker.
+type Pool struct {
+	tree           *tasktree.Tree
+	run            RunFunc
+	workers        int
+	perAgentLimit  int
+	queue          chan job
+	results        chan TaskResult
+	agentSemsMu   sync.Mutex
+	agentSems     map[string]chan struct{}
+	mu            sync.Mutex
+	batches       map[BatchID]*batchState
+	batchSeq      int
+}
+
+// New builds a Pool that dispatches work via run, records progress on tree,
+// runs `workers` goroutines pulling from the shared queue, and allows at
+// most perAgentLimit in-flight requests to any single agent address at a
+// time (<= 0 means unbounded per agent). Start must be called before any
+// SubmitBatch.
+func New(tree *tasktree.Tree, run RunFunc, workers, perAgentLimit int) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Pool{
+		tree:          tree,
+		run:           run,
+		workers:       workers,
+		perAgentLimit: perAgentLimit,
+		queue:         make(chan job, workers*4),
+		results:       make(chan TaskResult, workers*4),
+		agentSems:     make(map[string]chan struct{}),
+		batches:       make(map[BatchID]*batchState),
+	}
+}
+
+// Start launches the pool's worker goroutines. It returns immediately; the
+// workers run until ctx is cancelled.
+func (p *Pool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.worker(ctx)
+	}
+}
+
+// Results returns the channel every worker publishes completions to as they
+// happen. Callers should drain it continuously; a full buffer backs up into
+// the workers rather than dropping results.
+func (p *Pool) Results() <-chan TaskResult {
+	return p.results
+}
+
+// SubmitBatch enqueues reqs under a new BatchID and returns immediately
+// without waiting for any of them to run, let alone complete.
+func (p *Pool) SubmitBatch(ctx context.Context, reqs []TaskRequest) (BatchID, error) {
+	if len(reqs) == 0 {
+		return "", fmt.Errorf("pool: SubmitBatch called with no requests")
+	}
+
+	p.mu.Lock()
+	p.batchSeq++
+	id := BatchID(fmt.Sprintf("batch-%d", p.batchSeq))
+	batchCtx, cancel := context.WithCancel(ctx)
+	p.batches[id] = &batchState{total: len(reqs), pending: len(reqs), cancel: cancel}
+	p.mu.Unlock()
+
+	go func() {
+		for _, req := range reqs {
+			select {
+			case p.queue <- job{batch: id, req: req, ctx: batchCtx}:
+			case <-batchCtx.Done():
+				p.settlePending(id, TaskResult{BatchID: id, NodeID: req.NodeID, Err: batchCtx.Err()})
+			}
+		}
+	}()
+
+	return id, nil
+}
+
+// BatchStatus reports how many of a batch's requests are pending, running,
+// completed, or failed. It returns an error if id is unknown.
+func (p *Pool) BatchStatus(id BatchID) (BatchCounts, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.batches[id]
+	if !ok {
+		return BatchCounts{}, fmt.Errorf("pool: unknown batch %q", id)
+	}
+	return BatchCounts{Pending: b.pending, Running: b.running, Completed: b.completed, Failed: b.failed}, nil
+}
+
+// Cancel cancels id's batch context: requests still queued are settled as
+// failed without running, and requests already running see their ctx
+// cancelled (whether they stop early is up to the RunFunc). Every
+// request's result is still published so the caller can see what
+// happened to it.
+func (p *Pool) Cancel(id BatchID) error {
+	p.mu.Lock()
+	b, ok := p.batches[id]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("pool: unknown batch %q", id)
+	}
+	b.cancel()
+	return nil
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j, ok := <-p.queue:
+			if !ok {
+				return
+			}
+			p.runJob(j)
+		}
+	}
+}
+
+// runJob dispatches j.req via run, under j.ctx rather than the pool's own
+// lifetime context - j.ctx is the batch's context, so Cancel(j.batch) can
+// abort a job that's already running, not just ones still queued.
+func (p *Pool) runJob(j job) {
+	p.mu.Lock()
+	b := p.batches[j.batch]
+	if b != nil {
+		b.pending--
+		b.running++
+	}
+	p.mu.Unlock()
+
+	if j.ctx.Err() != nil {
+		p.settle(j.batch, TaskResult{BatchID: j.batch, NodeID: j.req.NodeID, Err: j.ctx.Err()})
+		return
+	}
+
+	if p.tree != nil {
+		p.tree.UpdateNodeStatus(j.req.NodeID, "running")
+	}
+
+	sem := p.agentSem(j.req.Agent)
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-j.ctx.Done():
+			p.settle(j.batch, TaskResult{BatchID: j.batch, NodeID: j.req.NodeID, Err: j.ctx.Err()})
+			return
+		}
+	}
+
+	content, success, err := p.run(j.ctx, j.req)
+
+	// The RunFunc owns terminal tasktree status (it knows the distinction
+	// between a "failed" business outcome, an "errored" infra outcome, and an
+	// "omitted" fast-fail skip); the pool only ever sets "running" above.
+
+	p.settle(j.batch, TaskResult{BatchID: j.batch, NodeID: j.req.NodeID, Content: content, Success: success, Err: err})
+}
+
+// settle records the outcome of a job that made it to "running" (i.e.
+// runJob already moved it out of pending).
+func (p *Pool) settle(id BatchID, res TaskResult) {
+	p.mu.Lock()
+	if b := p.batches[id]; b != nil {
+		b.running--
+	}
+	p.mu.Unlock()
+	p.settleCount(id, res)
+}
+
+// settlePending records the outcome of a job that was cancelled before it
+// ever left the queue (runJob, and so the pending->running transition,
+// never ran for it).
+func (p *Pool) settlePending(id BatchID, res TaskResult) {
+	p.mu.Lock()
+	if b := p.batches[id]; b != nil {
+		b.pending--
+	}
+	p.mu.Unlock()
+	p.settleCount(id, res)
+}
+
+func (p *Pool) settleCount(id BatchID, res TaskResult) {
+	p.mu.Lock()
+	if b := p.batches[id]; b != nil {
+		if res.Err != nil || !res.Success {
+			b.failed++
+		} else {
+			b.completed++
+		}
+	}
+	p.mu.Unlock()
+
+	p.results <- res
+}
+
+// agentSem returns (lazily creating) the semaphore that caps concurrent
+// in-flight requests to addr, or nil if the pool imposes no per-agent cap.
+func (p *Pool) agentSem(addr string) chan struct{} {
+	if p.perAgentLimit <= 0 || addr == "" {
+		return nil
+	}
+	p.agentSemsMu.Lock()
+	defer p.agentSemsMu.Unlock()
+	sem, ok := p.agentSems[addr]
+	if !ok {
+		sem = make(chan struct{}, p.perAgentLimit)
+		p.agentSems[addr] = sem
+	}
+	return sem
+}
@@ -1,41 +1,210 @@
 package tasktree
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
+
+	"agentic-engineering-system/logging"
 )
 
 // Node represents a single task in the hierarchy.
 type Node struct {
-	ID                string
-	ParentID          string
-	Persona           string
-	Instructions      string
-	Status            string // e.g., "pending", "running", "delegated", "completed", "failed"
+	ID           string
+	ParentID     string
+	Persona      string
+	Instructions string
+	// Status is one of "pending", "queued", "running", "delegated",
+	// "completed", "failed", "errored", "omitted", or "interrupted".
+	// "interrupted" is what Restore gives a node that was queued, running
+	// or delegated when its snapshot was taken. "failed" is an
+	// agent-reported business failure (the task ran but the agent says it
+	// didn't succeed); "errored" is an infrastructure failure (container
+	// spawn, gRPC, identity) that never got a real answer out of the agent.
+	// "omitted" means a fast-failing parent cancelled this node before it
+	// ever got to run.
+	Status            string
 	Result            string
 	SubTaskIDs        []string
 	RequiredSubTasks  int
 	CompletedSubTasks int
 	SubTaskResults    map[string]string // Map of SubTaskID to its result
-	lock              sync.Mutex
+	// ContinueOnFailed and ContinueOnErrored mark this node as non-critical
+	// to its parent: when set, this node ending in "failed" (respectively
+	// "errored") is excluded from GetFailedSubTasks, so it doesn't fail the
+	// parent or trigger fast-fail cancellation of its siblings. The Lead
+	// Agent sets these on a sub-task it considers optional.
+	ContinueOnFailed  bool
+	ContinueOnErrored bool
+	// Priority is a user-assignable base score a scheduler adds its own
+	// signals on top of (see schedule.DefaultScorer); it defaults to 0 and is
+	// otherwise untouched by the tree itself.
+	Priority float64
+	// ForceRun marks this node as user-flagged work that a priority
+	// scheduler should dispatch ahead of ordinary pending nodes.
+	ForceRun bool
+	// Attempts counts how many times this node has been (re)dispatched; a
+	// scheduler's retry penalty uses it to rank fresh work above retries.
+	Attempts int
+	// LastFailureReason and LastStatus record the outcome of the most
+	// recent attempt that ended before this one, so a scheduler's mishap
+	// bonus can tell a crash ("errored") apart from a normal business
+	// failure ("failed") even after the node has been requeued to
+	// "pending" for another try.
+	LastFailureReason string
+	LastStatus        string
+	// AttemptHistory is every attempt this node has gone through, oldest
+	// first, recorded by RecordAttempt. LastFailureReason/LastStatus above
+	// are a cheap summary of its last element; AttemptHistory is what a
+	// scheduler's retry-penalty scorer or the reconciler consult when the
+	// full sequence (not just the most recent outcome) matters.
+	AttemptHistory []AttemptRecord
+	// Deadline, if set, is when this node's result is no longer useful to
+	// its parent; a scheduler may use it to prioritize work that's close to
+	// missing it.
+	Deadline *time.Time
+	lock     sync.Mutex
+}
+
+// AttemptRecord is one retry attempt's outcome, appended to a Node's
+// AttemptHistory by RecordAttempt.
+type AttemptRecord struct {
+	Attempt int       `json:"attempt"`
+	Status  string    `json:"status"`
+	Reason  string    `json:"reason,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// EventKind identifies what kind of change an Event describes.
+type EventKind string
+
+const (
+	// Added is published once, right after a node is created.
+	Added EventKind = "added"
+	// StatusChanged is published by UpdateNodeStatus whenever it actually
+	// changes a node's Status (a no-op update to the same status doesn't
+	// publish). UpdateNodeResult reports its status change on its ResultSet
+	// event instead; RecordAttempt, MarkOmitted and Restore change Status
+	// without publishing any event.
+	StatusChanged EventKind = "status_changed"
+	// ResultSet is published when a node's Result is recorded.
+	ResultSet EventKind = "result_set"
+	// SubTaskLinked is published when a node gains a sub-task, either by
+	// being created with a ParentID (against the parent) or by having its
+	// RequiredSubTasks count set.
+	SubTaskLinked EventKind = "sub_task_linked"
+	// Removed is reserved for a future Tree.RemoveNode; no mutator in this
+	// package publishes it yet.
+	Removed EventKind = "removed"
+)
+
+// Event describes one change to a Tree, delivered to subscribers via
+// Tree.Watch.
+type Event struct {
+	Kind      EventKind
+	NodeID    string
+	ParentID  string
+	OldStatus string
+	NewStatus string
+	Result    string
+	Timestamp time.Time
 }
 
+// WatchFilter controls what a Watch subscription receives.
+type WatchFilter struct {
+	// IncludeExisting, if set, makes Watch synthesize an Added event for
+	// every node already in the tree before delivering live events, so a
+	// late-joining subscriber (e.g. a UI that connects after the tree has
+	// been running for a while) can build complete state without a
+	// separate Snapshot call racing against the live event stream.
+	IncludeExisting bool
+}
+
+// subscriberBufferSize bounds each Watch subscriber's channel; a subscriber
+// that falls behind by more than this many events has each new event
+// dropped (see publish) until it drains, rather than blocking every
+// mutator in the tree.
+const subscriberBufferSize = 64
+
 // Tree manages the entire task hierarchy.
 type Tree struct {
 	Nodes map[string]*Node // Map of TaskID to Node
 	lock  sync.RWMutex
+
+	subsMu sync.Mutex
+	subs   map[int]chan Event
+	subSeq int
 }
 
 func NewTree() *Tree {
 	return &Tree{
 		Nodes: make(map[string]*Node),
+		subs:  make(map[int]chan Event),
+	}
+}
+
+// Watch subscribes to the tree's change stream. The returned channel is
+// closed and the subscription removed when ctx is done; callers must keep
+// draining it (or cancel ctx) to avoid it filling up and dropping events.
+func (t *Tree) Watch(ctx context.Context, filter WatchFilter) (<-chan Event, error) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	t.subsMu.Lock()
+	id := t.subSeq
+	t.subSeq++
+	t.subs[id] = ch
+	t.subsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.subsMu.Lock()
+		delete(t.subs, id)
+		t.subsMu.Unlock()
+		close(ch)
+	}()
+
+	if filter.IncludeExisting {
+		for _, snap := range t.Snapshot() {
+			select {
+			case ch <- Event{
+				Kind:      Added,
+				NodeID:    snap.ID,
+				ParentID:  snap.ParentID,
+				NewStatus: snap.Status,
+				Result:    snap.Result,
+				Timestamp: time.Now(),
+			}:
+			case <-ctx.Done():
+				return ch, nil
+			}
+		}
+	}
+
+	return ch, nil
+}
+
+// publish delivers ev to every current subscriber without blocking: a
+// subscriber whose buffer is full has ev dropped and a warning logged,
+// rather than stalling the mutator that called publish. Callers must call
+// this after releasing whatever Node/Tree lock they were holding, so a slow
+// subscriber can never hold up an in-progress mutation.
+func (t *Tree) publish(ev Event) {
+	ev.Timestamp = time.Now()
+
+	t.subsMu.Lock()
+	defer t.subsMu.Unlock()
+	for id, ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+			logging.Root.Warn("tasktree: dropping event %s for node %s, subscriber %d is full", ev.Kind, ev.NodeID, id)
+		}
 	}
 }
 
 func (t *Tree) AddNode(parentID, persona, instructions string) *Node {
 	t.lock.Lock()
-	defer t.lock.Unlock()
 
 	node := &Node{
 		ID:             fmt.Sprintf("task-%d", time.Now().UnixNano()), // Unique ID
@@ -56,6 +225,13 @@ func (t *Tree) AddNode(parentID, persona, instructions string) *Node {
 		}
 	}
 
+	t.lock.Unlock()
+
+	t.publish(Event{Kind: Added, NodeID: node.ID, ParentID: parentID, NewStatus: node.Status})
+	if parentID != "" {
+		t.publish(Event{Kind: SubTaskLinked, NodeID: node.ID, ParentID: parentID})
+	}
+
 	return node
 }
 
@@ -70,38 +246,184 @@ func (t *Tree) UpdateNodeStatus(taskID, status string) {
 	node := t.Nodes[taskID]
 	t.lock.RUnlock()
 
+	if node == nil {
+		return
+	}
+
+	node.lock.Lock()
+	old := node.Status
+	node.Status = status
+	node.lock.Unlock()
+
+	if old != status {
+		t.publish(Event{Kind: StatusChanged, NodeID: taskID, ParentID: node.ParentID, OldStatus: old, NewStatus: status})
+	}
+}
+
+// UpdateNodeResult records a node's final output and, unless the caller
+// already set a terminal failure status ("failed" or "errored") for it,
+// marks it "completed" - so a caller that does
+// UpdateNodeStatus(id, "errored") immediately before UpdateNodeResult(id,
+// ...) doesn't have that status silently clobbered back to "completed".
+func (t *Tree) UpdateNodeResult(taskID, result string) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node == nil {
+		return
+	}
+
+	node.lock.Lock()
+	old := node.Status
+	node.Result = result
+	if node.Status != "failed" && node.Status != "errored" {
+		node.Status = "completed"
+	}
+	newStatus := node.Status
+	node.lock.Unlock()
+
+	t.publish(Event{Kind: ResultSet, NodeID: taskID, ParentID: node.ParentID, OldStatus: old, NewStatus: newStatus, Result: result})
+}
+
+func (t *Tree) SetRequiredSubTasks(taskID string, count int) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node == nil {
+		return
+	}
+
+	node.lock.Lock()
+	node.RequiredSubTasks = count
+	node.lock.Unlock()
+
+	t.publish(Event{Kind: SubTaskLinked, NodeID: taskID, ParentID: node.ParentID})
+}
+
+// SetContinueOn marks taskID as non-critical to its parent for the given
+// outcomes: a node with ContinueOnFailed (or ContinueOnErrored) set is
+// excluded from GetFailedSubTasks when it ends in that status, so its
+// parent neither fails nor fast-fail-cancels its siblings because of it.
+func (t *Tree) SetContinueOn(taskID string, continueOnFailed, continueOnErrored bool) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
 	if node != nil {
 		node.lock.Lock()
-		node.Status = status
+		node.ContinueOnFailed = continueOnFailed
+		node.ContinueOnErrored = continueOnErrored
 		node.lock.Unlock()
 	}
 }
 
-func (t *Tree) UpdateNodeResult(taskID, result string) {
+// SetPriority sets taskID's user-assignable base Priority.
+func (t *Tree) SetPriority(taskID string, priority float64) {
 	t.lock.RLock()
 	node := t.Nodes[taskID]
 	t.lock.RUnlock()
 
 	if node != nil {
 		node.lock.Lock()
-		node.Result = result
-		node.Status = "completed"
+		node.Priority = priority
 		node.lock.Unlock()
 	}
 }
 
-func (t *Tree) SetRequiredSubTasks(taskID string, count int) {
+// SetForceRun marks taskID as user-flagged for priority dispatch.
+func (t *Tree) SetForceRun(taskID string, forceRun bool) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node != nil {
+		node.lock.Lock()
+		node.ForceRun = forceRun
+		node.lock.Unlock()
+	}
+}
+
+// SetDeadline attaches (or clears, with nil) taskID's Deadline.
+func (t *Tree) SetDeadline(taskID string, deadline *time.Time) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node != nil {
+		node.lock.Lock()
+		node.Deadline = deadline
+		node.lock.Unlock()
+	}
+}
+
+// RecordAttempt bumps taskID's Attempts counter and records reason/status
+// from the attempt that just ended, then resets Status to "pending" so the
+// node becomes schedulable again. Callers retrying a node after a failure
+// should use this instead of UpdateNodeStatus directly, so the attempt
+// history a scheduler relies on doesn't get lost on requeue.
+func (t *Tree) RecordAttempt(taskID, reason, lastStatus string) {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node != nil {
+		node.lock.Lock()
+		node.Attempts++
+		node.LastFailureReason = reason
+		node.LastStatus = lastStatus
+		node.AttemptHistory = append(node.AttemptHistory, AttemptRecord{
+			Attempt: node.Attempts,
+			Status:  lastStatus,
+			Reason:  reason,
+			Time:    time.Now(),
+		})
+		node.Status = "pending"
+		node.lock.Unlock()
+	}
+}
+
+// MarkOmitted records that taskID was never run because its parent
+// fast-failed before this node reached the front of the queue.
+func (t *Tree) MarkOmitted(taskID, reason string) {
 	t.lock.RLock()
 	node := t.Nodes[taskID]
 	t.lock.RUnlock()
 
 	if node != nil {
 		node.lock.Lock()
-		node.RequiredSubTasks = count
+		node.Status = "omitted"
+		node.Result = reason
 		node.lock.Unlock()
 	}
 }
 
+// FailsParent reports whether taskID ended in a status that should count
+// against its parent: "failed" unless ContinueOnFailed is set, or "errored"
+// unless ContinueOnErrored is set. Used by a fast-failing parent to decide,
+// right after one child finishes, whether to cancel its remaining siblings.
+func (t *Tree) FailsParent(taskID string) bool {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node == nil {
+		return false
+	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	switch node.Status {
+	case "failed":
+		return !node.ContinueOnFailed
+	case "errored":
+		return !node.ContinueOnErrored
+	default:
+		return false
+	}
+}
+
 func (t *Tree) GetNodeStatus(taskID string) string {
 	t.lock.RLock()
 	node := t.Nodes[taskID]
@@ -139,7 +461,9 @@ func (t *Tree) GetSubTaskResults(taskID string) map[string]string {
 	return results
 }
 
-// GetFailedSubTasks returns the IDs of all failed sub-tasks
+// GetFailedSubTasks returns the IDs of all sub-tasks that ended "failed" or
+// "errored" and whose own ContinueOnFailed/ContinueOnErrored doesn't
+// tolerate that outcome - i.e. the sub-tasks that should fail this parent.
 func (t *Tree) GetFailedSubTasks(taskID string) []string {
 	t.lock.RLock()
 	node := t.Nodes[taskID]
@@ -155,7 +479,12 @@ func (t *Tree) GetFailedSubTasks(taskID string) []string {
 	var failed []string
 	for _, subTaskID := range node.SubTaskIDs {
 		subNode := t.Nodes[subTaskID]
-		if subNode != nil && subNode.Status == "failed" {
+		if subNode == nil {
+			continue
+		}
+		fails := (subNode.Status == "failed" && !subNode.ContinueOnFailed) ||
+			(subNode.Status == "errored" && !subNode.ContinueOnErrored)
+		if fails {
 			failed = append(failed, subTaskID)
 		}
 	}
@@ -163,6 +492,31 @@ func (t *Tree) GetFailedSubTasks(taskID string) []string {
 	return failed
 }
 
+// GetOmittedSubTasks returns the IDs of all sub-tasks that were never run
+// because a fast-failing parent cancelled them first (see MarkOmitted).
+func (t *Tree) GetOmittedSubTasks(taskID string) []string {
+	t.lock.RLock()
+	node := t.Nodes[taskID]
+	t.lock.RUnlock()
+
+	if node == nil {
+		return nil
+	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	var omitted []string
+	for _, subTaskID := range node.SubTaskIDs {
+		subNode := t.Nodes[subTaskID]
+		if subNode != nil && subNode.Status == "omitted" {
+			omitted = append(omitted, subTaskID)
+		}
+	}
+
+	return omitted
+}
+
 // GetCompletedSubTasks returns the IDs of all completed sub-tasks
 func (t *Tree) GetCompletedSubTasks(taskID string) []string {
 	t.lock.RLock()
@@ -186,3 +540,143 @@ func (t *Tree) GetCompletedSubTasks(taskID string) []string {
 
 	return completed
 }
+
+// NodeSnapshot is a lock-free, JSON-marshalable copy of a Node, for
+// read-only introspection (debug endpoints) where callers must not hold a
+// live Node's lock.
+type NodeSnapshot struct {
+	ID                string            `json:"id"`
+	ParentID          string            `json:"parentId"`
+	Persona           string            `json:"persona"`
+	Instructions      string            `json:"instructions"`
+	Status            string            `json:"status"`
+	Result            string            `json:"result,omitempty"`
+	SubTaskIDs        []string          `json:"subTaskIds,omitempty"`
+	RequiredSubTasks  int               `json:"requiredSubTasks"`
+	CompletedSubTasks int               `json:"completedSubTasks"`
+	SubTaskResults    map[string]string `json:"subTaskResults,omitempty"`
+	ContinueOnFailed  bool              `json:"continueOnFailed,omitempty"`
+	ContinueOnErrored bool              `json:"continueOnErrored,omitempty"`
+	Priority          float64           `json:"priority,omitempty"`
+	ForceRun          bool              `json:"forceRun,omitempty"`
+	Attempts          int               `json:"attempts,omitempty"`
+	LastFailureReason string            `json:"lastFailureReason,omitempty"`
+	LastStatus        string            `json:"lastStatus,omitempty"`
+	Deadline          *time.Time        `json:"deadline,omitempty"`
+	AttemptHistory    []AttemptRecord   `json:"attemptHistory,omitempty"`
+}
+
+// Snapshot returns a point-in-time, lock-free copy of every node in the
+// tree keyed by ID, safe to marshal or hold onto after the call returns.
+func (t *Tree) Snapshot() map[string]NodeSnapshot {
+	t.lock.RLock()
+	nodes := make([]*Node, 0, len(t.Nodes))
+	for _, node := range t.Nodes {
+		nodes = append(nodes, node)
+	}
+	t.lock.RUnlock()
+
+	out := make(map[string]NodeSnapshot, len(nodes))
+	for _, node := range nodes {
+		node.lock.Lock()
+		out[node.ID] = NodeSnapshot{
+			ID:                node.ID,
+			ParentID:          node.ParentID,
+			Persona:           node.Persona,
+			Instructions:      node.Instructions,
+			Status:            node.Status,
+			Result:            node.Result,
+			SubTaskIDs:        append([]string(nil), node.SubTaskIDs...),
+			RequiredSubTasks:  node.RequiredSubTasks,
+			CompletedSubTasks: node.CompletedSubTasks,
+			SubTaskResults:    node.SubTaskResults,
+			ContinueOnFailed:  node.ContinueOnFailed,
+			ContinueOnErrored: node.ContinueOnErrored,
+			Priority:          node.Priority,
+			ForceRun:          node.ForceRun,
+			Attempts:          node.Attempts,
+			LastFailureReason: node.LastFailureReason,
+			LastStatus:        node.LastStatus,
+			Deadline:          node.Deadline,
+			AttemptHistory:    append([]AttemptRecord(nil), node.AttemptHistory...),
+		}
+		node.lock.Unlock()
+	}
+
+	return out
+}
+
+// CountByStatus summarizes how many nodes are currently in each Status
+// value (e.g. {"queued": 4, "running": 2, "completed": 9}), cheap enough to
+// call on every status transition for a live "N running / M queued" view
+// without handing out live Node pointers.
+func (t *Tree) CountByStatus() map[string]int {
+	t.lock.RLock()
+	nodes := make([]*Node, 0, len(t.Nodes))
+	for _, node := range t.Nodes {
+		nodes = append(nodes, node)
+	}
+	t.lock.RUnlock()
+
+	counts := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		node.lock.Lock()
+		counts[node.Status]++
+		node.lock.Unlock()
+	}
+	return counts
+}
+
+// interruptedFrom maps a Snapshot's Status to what Restore should assign it,
+// so a node that was genuinely in flight when the snapshot was taken (as
+// opposed to one that had already settled) comes back as "interrupted"
+// rather than misleadingly still "running".
+func interruptedFrom(status string) string {
+	switch status {
+	case "queued", "running", "delegated":
+		return "interrupted"
+	default:
+		return status
+	}
+}
+
+// Restore replaces t's nodes with a copy of snapshot - e.g. to rehydrate a
+// Tree from a Snapshot() a caller persisted before the orchestrator
+// restarted. Every node that was genuinely in flight (not yet in a terminal
+// or not-yet-started status) comes back as "interrupted" instead of its
+// original status, so a scheduler sees it as needing a decision (retry or
+// resume) rather than mistaking it for still actively running.
+func (t *Tree) Restore(snapshot map[string]NodeSnapshot) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	nodes := make(map[string]*Node, len(snapshot))
+	for id, s := range snapshot {
+		subTaskResults := s.SubTaskResults
+		if subTaskResults == nil {
+			subTaskResults = make(map[string]string)
+		}
+		nodes[id] = &Node{
+			ID:                s.ID,
+			ParentID:          s.ParentID,
+			Persona:           s.Persona,
+			Instructions:      s.Instructions,
+			Status:            interruptedFrom(s.Status),
+			Result:            s.Result,
+			SubTaskIDs:        append([]string(nil), s.SubTaskIDs...),
+			RequiredSubTasks:  s.RequiredSubTasks,
+			CompletedSubTasks: s.CompletedSubTasks,
+			SubTaskResults:    subTaskResults,
+			ContinueOnFailed:  s.ContinueOnFailed,
+			ContinueOnErrored: s.ContinueOnErrored,
+			Priority:          s.Priority,
+			ForceRun:          s.ForceRun,
+			Attempts:          s.Attempts,
+			LastFailureReason: s.LastFailureReason,
+			LastStatus:        s.LastStatus,
+			Deadline:          s.Deadline,
+			AttemptHistory:    append([]AttemptRecord(nil), s.AttemptHistory...),
+		}
+	}
+	t.Nodes = nodes
+}
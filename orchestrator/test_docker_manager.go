@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"agentic-engineering-system/docker"
+	"agentic-engineering-system/identity"
 	pb "agentic-engineering-system/proto/agentic-engineering-system/proto"
 
 	"google.golang.org/grpc"
@@ -14,7 +16,7 @@ import (
 
 func dockerManagerTest() {
 	ctx := context.Background()
-	dockerManager, err := docker.NewManager(ctx)
+	dockerManager, err := docker.NewManager(ctx, docker.DefaultRuntimeConfig())
 	if err != nil {
 		log.Fatalf("Failed to create docker manager: %v", err)
 	}
@@ -22,10 +24,12 @@ func dockerManagerTest() {
 
 	// Spawn agent
 	log.Printf("🐳 Spawning agent container...")
-	agentContainer, err := dockerManager.SpawnAgent(ctx)
+	spiffeID := fmt.Sprintf("spiffe://%s/debug/docker-manager-test", identity.TrustDomain)
+	agentContainer, _, caps, err := dockerManager.SpawnAgent(ctx, spiffeID)
 	if err != nil {
 		log.Fatalf("Failed to spawn agent container: %v", err)
 	}
+	log.Printf("🤝 Agent advertised capabilities: %+v", caps)
 	defer func() {
 		log.Printf("🧹 Cleaning up agent container %s", agentContainer.ID[:12])
 		if err := dockerManager.StopAgent(ctx, agentContainer.ID); err != nil {
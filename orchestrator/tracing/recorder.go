@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// taskIDAttrKey is the attribute every root/child/grandchild span in this
+// package is tagged with, so the recorder can group spans by TaskExecution
+// regardless of trace ID (useful once a collector is also configured and
+// the same trace gets sampled/exported independently).
+const taskIDAttrKey = attribute.Key("task.id")
+
+// SpanEvent is a single timestamped annotation on a span, e.g. a Docker
+// spawn/stop or a phase approval/rejection.
+type SpanEvent struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SpanRecord is the JSON-serializable shape of a single completed span,
+// returned by /api/task/{id}/trace so the UI can render a trace tree even
+// when no OTLP/Zipkin collector is configured to receive one.
+type SpanRecord struct {
+	Name         string            `json:"name"`
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	TraceID      string            `json:"traceId"`
+	StartTime    time.Time         `json:"startTime"`
+	EndTime      time.Time         `json:"endTime"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Events       []SpanEvent       `json:"events,omitempty"`
+}
+
+// recorder keeps every completed span in memory, indexed by its task.id
+// attribute.
+type recorder struct {
+	lock   sync.RWMutex
+	byTask map[string][]SpanRecord
+}
+
+var defaultRecorder = &recorder{byTask: make(map[string][]SpanRecord)}
+
+// recorderProcessor is a minimal sdktrace.SpanProcessor that mirrors every
+// ended span into defaultRecorder. It runs alongside (not instead of)
+// whatever batch exporter Init configures.
+type recorderProcessor struct{}
+
+func newRecorderProcessor() sdktrace.SpanProcessor { return &recorderProcessor{} }
+
+func (p *recorderProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+func (p *recorderProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	record := SpanRecord{
+		Name:       s.Name(),
+		SpanID:     s.SpanContext().SpanID().String(),
+		TraceID:    s.SpanContext().TraceID().String(),
+		StartTime:  s.StartTime(),
+		EndTime:    s.EndTime(),
+		Attributes: make(map[string]string),
+	}
+	if s.Parent().HasSpanID() {
+		record.ParentSpanID = s.Parent().SpanID().String()
+	}
+
+	taskID := record.TraceID // Fall back to grouping by trace if task.id is absent.
+	for _, kv := range s.Attributes() {
+		record.Attributes[string(kv.Key)] = kv.Value.Emit()
+		if kv.Key == taskIDAttrKey {
+			taskID = kv.Value.AsString()
+		}
+	}
+	for _, ev := range s.Events() {
+		event := SpanEvent{Name: ev.Name, Time: ev.Time, Attributes: make(map[string]string)}
+		for _, kv := range ev.Attributes {
+			event.Attributes[string(kv.Key)] = kv.Value.Emit()
+		}
+		record.Events = append(record.Events, event)
+	}
+
+	defaultRecorder.lock.Lock()
+	defaultRecorder.byTask[taskID] = append(defaultRecorder.byTask[taskID], record)
+	defaultRecorder.lock.Unlock()
+}
+
+func (p *recorderProcessor) Shutdown(ctx context.Context) error   { return nil }
+func (p *recorderProcessor) ForceFlush(ctx context.Context) error { return nil }
+
+// Trace returns every recorded span belonging to taskID, in completion
+// order; callers wanting a tree should sort by StartTime and link via
+// ParentSpanID themselves.
+func Trace(taskID string) []SpanRecord {
+	defaultRecorder.lock.RLock()
+	defer defaultRecorder.lock.RUnlock()
+
+	spans := defaultRecorder.byTask[taskID]
+	out := make([]SpanRecord, len(spans))
+	copy(out, spans)
+	return out
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartTaskSpan opens the root span for a TaskExecution.
+func StartTaskSpan(ctx context.Context, taskID, task string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "orchestrator.task",
+		trace.WithAttributes(
+			taskIDAttrKey.String(taskID),
+			attribute.String("task.description", task),
+		),
+	)
+}
+
+// StartPhaseSpan opens a child span for a single ProjectPhase.
+func StartPhaseSpan(ctx context.Context, taskID, phaseID, phaseName string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "orchestrator.phase",
+		trace.WithAttributes(
+			taskIDAttrKey.String(taskID),
+			attribute.String("phase.id", phaseID),
+			attribute.String("phase.name", phaseName),
+		),
+	)
+}
+
+// StartExpertSpan opens a grandchild span for a single DomainExpert run.
+func StartExpertSpan(ctx context.Context, taskID, phaseID, role string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "orchestrator.expert",
+		trace.WithAttributes(
+			taskIDAttrKey.String(taskID),
+			attribute.String("phase.id", phaseID),
+			attribute.String("expert.role", role),
+		),
+	)
+}
+
+// StartAgentCallSpan opens a span around one ExecuteTaskOnAgent attempt, so
+// a trace viewer can tell which retry of which tasktree node a given gRPC
+// call (itself further instrumented by otelgrpc's stats handler) belongs
+// to, reconstructing the full delegation DAG from tasktree alone.
+func StartAgentCallSpan(ctx context.Context, nodeID string, attempt int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "orchestrator.agent_call",
+		trace.WithAttributes(
+			attribute.String("node.id", nodeID),
+			attribute.Int("attempt", attempt),
+		),
+	)
+}
+
+// StartDBSpan opens a span around a single durable-storage call (taskStore
+// or the legacy database package), tagging it with the operation name
+// ("save_task", "get_task", ...) and the task ID it read or wrote.
+func StartDBSpan(ctx context.Context, op, taskID string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "orchestrator.db."+op,
+		trace.WithAttributes(
+			taskIDAttrKey.String(taskID),
+		),
+	)
+}
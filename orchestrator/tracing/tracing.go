@@ -0,0 +1,100 @@
+// Package tracing wires the orchestrator into OpenTelemetry: each
+// TaskExecution becomes a root span, each ProjectPhase a child span, and
+// each DomainExpert run a grandchild span, so the whole delegation tree
+// shows up in a trace viewer instead of only in log lines.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "agentic-engineering-system/orchestrator"
+
+var tracer = otel.Tracer(tracerName)
+
+// Tracer returns the orchestrator's single shared tracer.
+func Tracer() trace.Tracer { return tracer }
+
+// Init wires up the global TracerProvider from environment configuration:
+//
+//   - OTEL_EXPORTER_OTLP_ENDPOINT (+ optional OTEL_EXPORTER_OTLP_PROTOCOL,
+//     "grpc" or the default "http/protobuf") ships spans to an OTLP
+//     collector.
+//   - OTEL_EXPORTER_ZIPKIN_ENDPOINT is used instead when no OTLP endpoint is
+//     set, for teams still running a Zipkin collector.
+//   - With neither set, spans are still generated and kept in the
+//     in-memory recorder backing /api/task/{id}/trace; they're just never
+//     shipped off-box.
+//
+// The returned func flushes and stops the provider and should be deferred
+// by the caller.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "agentic-engineering-system-orchestrator"
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceNameKey.String(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OTEL resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(newRecorderProcessor()),
+	}
+
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OTEL exporter: %w", err)
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	} else {
+		log.Printf("⚠️ No OTEL exporter configured (set OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_ZIPKIN_ENDPOINT); spans are only kept in the in-memory trace recorder")
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp.Shutdown, nil
+}
+
+// newExporter returns nil, nil when no exporter is configured, which Init
+// treats as "recorder only".
+func newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		if os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL") == "grpc" {
+			log.Printf("📡 Exporting traces via OTLP/gRPC to %s", endpoint)
+			return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+		}
+		log.Printf("📡 Exporting traces via OTLP/HTTP to %s", endpoint)
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_ZIPKIN_ENDPOINT"); endpoint != "" {
+		log.Printf("📡 Exporting traces via Zipkin to %s", endpoint)
+		return zipkin.New(endpoint)
+	}
+
+	return nil, nil
+}